@@ -0,0 +1,126 @@
+package supd
+
+import (
+	"time"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaycc/supd/rpcclient"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/fsnotify.v1"
+)
+
+// true if the config is reloaded automatically after the config files are changed
+func (s *Supervisor) isAutoReload() bool {
+	entry, ok := s.config.GetSupervisord()
+	return ok && entry.GetBool("auto_reload", false)
+}
+
+// get the delay of the auto reload, the config is reloaded after the changes are quiet for the delay.
+func (s *Supervisor) getAutoReloadDelay() time.Duration {
+	entry, ok := s.config.GetSupervisord()
+	if !ok {
+		return time.Second
+	}
+	return time.Duration(entry.GetInt("auto_reload_delay", 1)) * time.Second
+}
+
+// watch the config file and the directories of the included files if auto_reload is enabled,
+// it is called after the config is loaded because the [include] section may be changed.
+func (s *Supervisor) watchConfig() {
+	if !s.isAutoReload() {
+		return
+	}
+	if s.configWatcher == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Error("fail to watch the config files:", errors.As(err))
+			return
+		}
+		s.configWatcher = watcher
+		go s.runWatchConfig(watcher)
+	}
+	for _, dir := range s.config.GetConfigDirs() {
+		if err := s.configWatcher.Add(dir); err != nil {
+			log.WithFields(log.Fields{"path": dir}).Warn("fail to watch the directory of config files:", err)
+		}
+	}
+}
+
+func (s *Supervisor) stopWatchConfig() {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	if s.configWatcher != nil {
+		s.configWatcher.Close()
+		s.configWatcher = nil
+	}
+}
+
+func (s *Supervisor) runWatchConfig(watcher *fsnotify.Watcher) {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if !s.isAutoReload() || !s.config.IsConfigFile(event.Name) {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(s.getAutoReloadDelay(), s.autoReload)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("fail to watch the config files:", err)
+		}
+	}
+}
+
+// reload the config like ctl reload, the changed config is rejected if it is bad
+// and the running programs are not affected.
+func (s *Supervisor) autoReload() {
+	if s.isRestarting() {
+		return
+	}
+	if err := s.config.Check(); err != nil {
+		log.Error("the changed config is rejected, keep the running programs:", err)
+		return
+	}
+	log.Info("the config files are changed, reload the config automatically")
+	reply := &rpcclient.ReloadConfigRet{}
+	if err := s.ReloadConfig(&rpcclient.ReloadConfigArg{}, reply); err != nil {
+		log.Error("fail to reload the config automatically:", err)
+	}
+}
+
+// get the names of the added, changed and removed programs
+func diffPrograms(prevPrograms []*config.ConfigEntry, curPrograms []*config.ConfigEntry) (added []string, changed []string, removed []string) {
+	prev := map[string]*config.ConfigEntry{}
+	for _, entry := range prevPrograms {
+		prev[entry.GetProgramName()] = entry
+	}
+	cur := map[string]bool{}
+	for _, entry := range curPrograms {
+		name := entry.GetProgramName()
+		cur[name] = true
+		prevEntry, ok := prev[name]
+		if !ok {
+			added = append(added, name)
+		} else if !prevEntry.Equal(entry) {
+			changed = append(changed, name)
+		}
+	}
+	for _, entry := range prevPrograms {
+		if !cur[entry.GetProgramName()] {
+			removed = append(removed, entry.GetProgramName())
+		}
+	}
+	return added, changed, removed
+}
@@ -20,6 +20,8 @@ type ConfigEntry struct {
 	ConfigDir string
 	Group     string
 	Name      string
+	// the name of the program section, the numprocs instances of a program have the same Program
+	Program   string
 	keyValues map[string]string
 }
 
@@ -45,6 +47,16 @@ func (c *ConfigEntry) GetEventListenerName() string {
 	return ""
 }
 
+// true if the entry is the [proxy:name] section
+func (c *ConfigEntry) IsProxy() bool {
+	return strings.HasPrefix(c.Name, "proxy:")
+}
+
+// get the name of the proxy
+func (c *ConfigEntry) GetProxyName() string {
+	return strings.TrimPrefix(c.Name, "proxy:")
+}
+
 func (c *ConfigEntry) IsGroup() bool {
 	return strings.HasPrefix(c.Name, "group:")
 }
@@ -77,7 +89,7 @@ func (c *ConfigEntry) KeyValues() map[string]string {
 	return c.keyValues
 }
 
-// dump the configuration as string
+// dump the configuration as string, the secret references are redacted.
 func (c *ConfigEntry) String() string {
 	buf := bytes.NewBuffer(make([]byte, 0))
 	fmt.Fprintf(buf, "configDir=%s\n", c.ConfigDir)
@@ -89,26 +101,76 @@ func (c *ConfigEntry) String() string {
 	sort.Strings(keys)
 	for _, k := range keys {
 		v, _ := c.keyValues[k]
-		fmt.Fprintf(buf, "%s=%s\n", k, v)
+		fmt.Fprintf(buf, "%s=%s\n", k, RedactSecret(v))
 	}
 
 	return buf.String()
 }
 
+// check if the configuration is same as the other one.
+func (c *ConfigEntry) Equal(other *ConfigEntry) bool {
+	if c.ConfigDir != other.ConfigDir || c.Group != other.Group || c.Name != other.Name || c.Program != other.Program || len(c.keyValues) != len(other.keyValues) {
+		return false
+	}
+	for k, v := range c.keyValues {
+		if ov, ok := other.keyValues[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangedKeys get the keys which are added, removed or changed in the other entry.
+func (c *ConfigEntry) ChangedKeys(other *ConfigEntry) []string {
+	keys := []string{}
+	for k, v := range c.keyValues {
+		if ov, ok := other.keyValues[k]; !ok || ov != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range other.keyValues {
+		if _, ok := c.keyValues[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 type Config struct {
 	configFile string
 	//mapping between the section name and the configure
 	entries map[string]*ConfigEntry
 
 	ProgramGroup *ProcessGroup
+
+	// the templates of the programs for scaling at runtime
+	programTemplates map[string]*programTemplate
+	// the numprocs scaled at runtime
+	numprocs map[string]scaledNumprocs
+	// the [program:name@] template sections
+	instanceTemplates map[string]*ini.Section
+	// the instances created from the templates at runtime
+	instances map[string]bool
+	// the ports allocated to the process instances by port_range or port_base
+	ports map[string]int
 }
 
 func NewConfigEntry(configDir string) *ConfigEntry {
-	return &ConfigEntry{configDir, "", "", make(map[string]string)}
+	return &ConfigEntry{ConfigDir: configDir, keyValues: make(map[string]string)}
 }
 
 func NewConfig(configFile string) *Config {
-	return &Config{configFile, make(map[string]*ConfigEntry), NewProcessGroup()}
+	return &Config{
+		configFile:        configFile,
+		entries:           make(map[string]*ConfigEntry),
+		ProgramGroup:      NewProcessGroup(),
+		programTemplates:  make(map[string]*programTemplate),
+		numprocs:          make(map[string]scaledNumprocs),
+		instanceTemplates: make(map[string]*ini.Section),
+		instances:         make(map[string]bool),
+		ports:             make(map[string]int),
+	}
 }
 
 //create a new entry or return the already-exist entry
@@ -125,8 +187,32 @@ func (c *Config) createEntry(name string, configDir string) *ConfigEntry {
 //
 // return the loaded programs
 func (c *Config) Load() ([]string, error) {
+	cfg, err := c.loadIni(false)
+	if err != nil {
+		return nil, errors.As(err)
+	}
 	c.ProgramGroup = NewProcessGroup()
+	return c.parse(cfg), nil
+}
+
+// check the config files without loading them, the bad included files are rejected instead of skipped.
+func (c *Config) Check() error {
+	cfg, err := c.loadIni(true)
+	if err != nil {
+		return errors.As(err)
+	}
+	checked := NewConfig(c.configFile)
+	checked.parse(cfg)
+	for _, entry := range checked.GetPrograms() {
+		if entry.GetString("command", "") == "" {
+			return errors.New("the command of program is not configured").As(entry.GetProgramName())
+		}
+	}
+	return nil
+}
 
+// load the config file and append the included files, the bad included files are skipped if not strict.
+func (c *Config) loadIni(strict bool) (*ini.File, error) {
 	// decode supd config
 	cfg, err := ini.InsensitiveLoad(c.configFile)
 	if err != nil {
@@ -152,6 +238,9 @@ func (c *Config) Load() ([]string, error) {
 
 		// checksum ini format
 		if _, err := ini.InsensitiveLoad(dData); err != nil {
+			if strict {
+				return nil, errors.As(err, f)
+			}
 			log.Warnf("Error ini file:%s", f)
 			continue
 		}
@@ -159,40 +248,59 @@ func (c *Config) Load() ([]string, error) {
 			return nil, errors.As(err, f)
 		}
 	}
-	return c.parse(cfg), nil
+	return cfg, nil
 }
 
-func (c *Config) getIncludeFiles(cfg *ini.File) []string {
-	result := make([]string, 0)
+// the pattern of the included files
+type includePattern struct {
+	dir  string
+	name *regexp.Regexp
+}
+
+func (p *includePattern) match(name string) bool {
+	return p.name.FindString(name) == name
+}
+
+func (c *Config) getIncludePatterns(cfg *ini.File) []*includePattern {
+	result := make([]*includePattern, 0)
 	if includeSection, err := cfg.GetSection("include"); err == nil {
 		key, err := includeSection.GetKey("files")
 		if err == nil {
 			env := NewStringExpression("here", c.GetConfigFileDir())
 			files := strings.Fields(os.ExpandEnv(key.Value()))
 			for _, f_raw := range files {
-				dir := c.GetConfigFileDir()
 				f, err := env.Eval(f_raw)
 				if err != nil {
 					continue
 				}
-				if filepath.IsAbs(f) {
-					dir = filepath.Dir(f)
+				// the relative path is relative to the directory of the config file
+				dir := filepath.Dir(f)
+				if !filepath.IsAbs(f) {
+					dir = filepath.Join(c.GetConfigFileDir(), dir)
 				}
-				fileInfos, err := ioutil.ReadDir(dir)
-				if err == nil {
-					goPattern := toRegexp(filepath.Base(f))
-					r, err := regexp.Compile(goPattern)
-					if err != nil {
-						continue
-					}
-					for _, fileInfo := range fileInfos {
-						name := fileInfo.Name()
-						if r.FindString(name) == name {
-							result = append(result, filepath.Join(dir, name))
-						}
-					}
+				goPattern := toRegexp(filepath.Base(f))
+				r, err := regexp.Compile(goPattern)
+				if err != nil {
+					continue
 				}
+				result = append(result, &includePattern{dir: dir, name: r})
+			}
+		}
+	}
+	return result
+}
 
+func (c *Config) getIncludeFiles(cfg *ini.File) []string {
+	result := make([]string, 0)
+	for _, pattern := range c.getIncludePatterns(cfg) {
+		fileInfos, err := ioutil.ReadDir(pattern.dir)
+		if err != nil {
+			continue
+		}
+		for _, fileInfo := range fileInfos {
+			name := fileInfo.Name()
+			if pattern.match(name) {
+				result = append(result, filepath.Join(pattern.dir, name))
 			}
 		}
 	}
@@ -200,16 +308,60 @@ func (c *Config) getIncludeFiles(cfg *ini.File) []string {
 
 }
 
+// get the directories of the config file and the included files
+func (c *Config) GetConfigDirs() []string {
+	dirs := []string{filepath.Dir(c.configFile)}
+	cfg, err := ini.InsensitiveLoad(c.configFile)
+	if err != nil {
+		return dirs
+	}
+	for _, pattern := range c.getIncludePatterns(cfg) {
+		dirs = append(dirs, pattern.dir)
+	}
+	return dirs
+}
+
+// true if the file is the config file or it matches the files of the [include] section
+func (c *Config) IsConfigFile(file string) bool {
+	if filepath.Clean(file) == filepath.Clean(c.configFile) {
+		return true
+	}
+	cfg, err := ini.InsensitiveLoad(c.configFile)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range c.getIncludePatterns(cfg) {
+		if filepath.Clean(pattern.dir) == filepath.Dir(filepath.Clean(file)) && pattern.match(filepath.Base(file)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) parse(cfg *ini.File) []string {
 	c.parseGroup(cfg)
 	loaded_programs := c.parseProgram(cfg)
 
 	//parse non-group,non-program and non-eventlistener sections
+	proxies := map[string]bool{}
 	for _, section := range cfg.Sections() {
 		if !strings.HasPrefix(section.Name(), "group:") && !strings.HasPrefix(section.Name(), "program:") && !strings.HasPrefix(section.Name(), "eventlistener:") {
+			if strings.HasPrefix(section.Name(), "proxy:") {
+				// the running proxy keeps the entry it is started with
+				delete(c.entries, section.Name())
+			}
 			entry := c.createEntry(section.Name(), c.GetConfigFileDir())
 			c.entries[section.Name()] = entry
 			entry.parse(section)
+			if entry.IsProxy() {
+				proxies[section.Name()] = true
+			}
+		}
+	}
+	// the removed proxies are not kept like the other sections
+	for name, entry := range c.entries {
+		if entry.IsProxy() && !proxies[name] {
+			delete(c.entries, name)
 		}
 	}
 	return loaded_programs
@@ -267,6 +419,13 @@ func (c *Config) GetGroups() []*ConfigEntry {
 	})
 }
 
+// get the [proxy:name] sections
+func (c *Config) GetProxies() []*ConfigEntry {
+	return c.GetEntries(func(entry *ConfigEntry) bool {
+		return entry.IsProxy()
+	})
+}
+
 func (c *Config) GetPrograms() []*ConfigEntry {
 	programs := c.GetEntries(func(entry *ConfigEntry) bool {
 		return entry.IsProgram()
@@ -286,6 +445,7 @@ func (c *Config) ClonePrograms() []*ConfigEntry {
 			ConfigDir: v.ConfigDir,
 			Group:     v.Group,
 			Name:      v.Name,
+			Program:   v.Program,
 			keyValues: vals,
 		}
 	}
@@ -359,45 +519,21 @@ func (c *ConfigEntry) GetInt(key string, defValue int) int {
 }
 
 // GetEnv get the value of key as environment setting. An environment string example:
-//  environment = A="env 1",B="this is a test"
+//  environment = A="env 1",B="this is a test",C="a,b"
+//
+// A value can be quoted with '"' to contain ','. The quotes and escapes of the dotenv syntax
+// are only for the env_file, see ParseDotenv.
 func (c *ConfigEntry) GetEnv(key string) []string {
 	value, ok := c.keyValues[key]
 	env := make([]string, 0)
 
 	if ok {
-		start := 0
-		n := len(value)
-		var i int
-		for {
-			for i = start; i < n && value[i] != '='; {
-				i++
-			}
-			key := value[start:i]
-			start = i + 1
-			if value[start] == '"' {
-				for i = start + 1; i < n && value[i] != '"'; {
-					i++
-				}
-				if i < n {
-					env = append(env, fmt.Sprintf("%s=%s", strings.TrimSpace(key), strings.TrimSpace(value[start+1:i])))
-				}
-				if i+1 < n && value[i+1] == ',' {
-					start = i + 2
-				} else {
-					break
-				}
-			} else {
-				for i = start; i < n && value[i] != ','; {
-					i++
-				}
-				if i < n {
-					env = append(env, fmt.Sprintf("%s=%s", strings.TrimSpace(key), strings.TrimSpace(value[start:i])))
-					start = i + 1
-				} else {
-					env = append(env, fmt.Sprintf("%s=%s", strings.TrimSpace(key), strings.TrimSpace(value[start:])))
-					break
-				}
+		for _, kv := range splitEnv(value) {
+			pos := strings.Index(kv, "=")
+			if pos == -1 {
+				continue
 			}
+			env = append(env, fmt.Sprintf("%s=%s", strings.TrimSpace(kv[:pos]), kv[pos+1:]))
 		}
 	}
 
@@ -406,7 +542,8 @@ func (c *ConfigEntry) GetEnv(key string) []string {
 		tmp, err := NewStringExpression("program_name", c.GetProgramName(),
 			"process_num", c.GetString("process_num", "0"),
 			"group_name", c.GetGroupName(),
-			"here", c.ConfigDir).Eval(env[i])
+			"here", c.ConfigDir,
+			"port", c.GetString("port", "")).Eval(env[i])
 		if err == nil {
 			result = append(result, tmp)
 		}
@@ -414,6 +551,64 @@ func (c *ConfigEntry) GetEnv(key string) []string {
 	return result
 }
 
+// split the environment string by ',' like the older supd, a value starting with '"' is read to the
+// next '"' and it may contain ','. The '\'' and '\' are not special, e.g. PATH=C:\tools,MSG=don't.
+func splitEnv(value string) []string {
+	result := make([]string, 0)
+	start := 0
+	n := len(value)
+	for start < n {
+		i := start
+		for i < n && value[i] != '=' && value[i] != ',' {
+			i++
+		}
+		if i >= n || value[i] == ',' {
+			// the item without '='
+			if item := strings.TrimSpace(value[start:i]); item != "" {
+				result = append(result, item)
+			}
+			start = i + 1
+			continue
+		}
+		key := strings.TrimSpace(value[start:i])
+		start = i + 1
+		for start < n && value[start] == ' ' {
+			start++
+		}
+		if start < n && value[start] == '"' {
+			i = strings.IndexByte(value[start+1:], '"')
+			if i == -1 {
+				// the quote is not closed
+				result = append(result, key+"="+strings.TrimSpace(value[start+1:]))
+				break
+			}
+			result = append(result, key+"="+strings.TrimSpace(value[start+1:start+1+i]))
+			start = start + 1 + i + 1
+			// skip to the next item
+			for start < n && value[start] != ',' {
+				start++
+			}
+			start++
+			continue
+		}
+		i = start
+		for i < n && value[i] != ',' {
+			i++
+		}
+		result = append(result, trimEnvItem(key+"="+value[start:i]))
+		start = i + 1
+	}
+	return result
+}
+
+func trimEnvItem(item string) string {
+	pos := strings.Index(item, "=")
+	if pos == -1 {
+		return strings.TrimSpace(item)
+	}
+	return strings.TrimSpace(item[:pos]) + "=" + strings.TrimSpace(item[pos+1:])
+}
+
 //get the value of key as string
 func (c *ConfigEntry) GetString(key string, defValue string) string {
 	s, ok := c.keyValues[key]
@@ -448,7 +643,8 @@ func (c *ConfigEntry) GetStringExpression(key string, defValue string) string {
 		"process_num", c.GetString("process_num", "0"),
 		"group_name", c.GetGroupName(),
 		"here", c.ConfigDir,
-		"host_node_name", host_name).Eval(s)
+		"host_node_name", host_name,
+		"port", c.GetString("port", "")).Eval(s)
 
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -543,15 +739,42 @@ func (c *Config) isProgramOrEventListener(section *ini.Section) (bool, string) {
 	return is_program || is_event_listener, prefix
 }
 
+// the template to create the numprocs instances of a program
+type programTemplate struct {
+	prefix      string
+	programName string
+	section     *ini.Section
+	// the raw values, the expressions are evaluated for each process.
+	procName string
+	command  string
+	numprocs int
+	// the numprocs in the configuration file
+	confNumprocs int
+}
+
+// the numprocs scaled at runtime, it is dropped if the numprocs in the configuration file is changed
+type scaledNumprocs struct {
+	numprocs     int
+	confNumprocs int
+}
+
 // parse the sections starts with "program:" prefix.
 //
 // Return all the parsed program names in the ini and the file hash
 func (c *Config) parseProgram(cfg *ini.File) []string {
 	loaded_programs := make([]string, 0)
+	c.programTemplates = make(map[string]*programTemplate)
+	c.instanceTemplates = make(map[string]*ini.Section)
 	for _, section := range cfg.Sections() {
 
 		program_or_event_listener, prefix := c.isProgramOrEventListener(section)
 
+		// the template is instantiated at runtime
+		if prefix == "program:" && strings.HasSuffix(section.Name(), INSTANCE_SEP) {
+			c.instanceTemplates[strings.TrimSuffix(section.Name()[len(prefix):], INSTANCE_SEP)] = section
+			continue
+		}
+
 		//if it is program or event listener
 		if program_or_event_listener {
 			//get the number of processes
@@ -560,52 +783,182 @@ func (c *Config) parseProgram(cfg *ini.File) []string {
 			if err != nil {
 				numProcs = 1
 			}
+			confNumprocs := numProcs
+			// the numprocs is scaled at runtime
+			if scaled, ok := c.numprocs[programName]; ok {
+				if scaled.confNumprocs != confNumprocs {
+					log.WithFields(log.Fields{
+						"program":  programName,
+						"numprocs": confNumprocs,
+					}).Info("the numprocs in the configuration is changed, drop the numprocs scaled at runtime")
+					delete(c.numprocs, programName)
+				} else {
+					if scaled.numprocs != confNumprocs {
+						log.WithFields(log.Fields{
+							"program":  programName,
+							"numprocs": scaled.numprocs,
+						}).Info("the numprocs in the configuration is overridden by the numprocs scaled at runtime")
+					}
+					numProcs = scaled.numprocs
+				}
+			}
 			procName, err := section.GetKey("process_name")
 			if numProcs > 1 {
-				if err != nil || strings.Index(procName.String(), "%(process_num)") == -1 {
+				if err != nil || strings.Index(procName.Value(), "%(process_num)") == -1 {
 					log.WithFields(log.Fields{
 						"numprocs":     numProcs,
 						"process_name": procName,
 					}).Error("no process_num in process name")
 				}
 			}
-			originalProcName := programName
+			tmpl := &programTemplate{
+				prefix:       prefix,
+				programName:  programName,
+				section:      section,
+				procName:     programName,
+				command:      section.Key("command").Value(),
+				numprocs:     numProcs,
+				confNumprocs: confNumprocs,
+			}
 			if err == nil {
-				originalProcName = procName.String()
+				tmpl.procName = procName.Value()
 			}
+			c.programTemplates[programName] = tmpl
 
 			for i := 1; i <= numProcs; i++ {
-				envs := NewStringExpression("program_name", programName,
-					"process_num", fmt.Sprintf("%d", i),
-					"group_name", c.ProgramGroup.GetGroup(programName, programName),
-					"here", c.GetConfigFileDir())
-				cmd, err := envs.Eval(section.Key("command").String())
+				procName, err := c.createProgramInstance(tmpl, i)
 				if err != nil {
 					continue
 				}
-				section.NewKey("command", cmd)
-
-				procName, err := envs.Eval(originalProcName)
-				if err != nil {
-					continue
-				}
-
-				section.NewKey("process_name", procName)
-				section.NewKey("numprocs_start", fmt.Sprintf("%d", (i-1)))
-				section.NewKey("process_num", fmt.Sprintf("%d", i))
-				entry := c.createEntry(procName, c.GetConfigFileDir())
-				entry.parse(section)
-				entry.Name = prefix + procName
-				group := c.ProgramGroup.GetGroup(programName, programName)
-				entry.Group = group
 				loaded_programs = append(loaded_programs, procName)
 			}
 		}
 	}
+	// create the instances again, the instance is removed if its template is removed
+	for name := range c.instances {
+		if _, err := c.CreateInstance(name); err != nil {
+			log.WithFields(log.Fields{"program": name}).Warn("the instance is removed:", err)
+			delete(c.instances, name)
+			continue
+		}
+		loaded_programs = append(loaded_programs, name)
+	}
+	// release the ports of the removed instances
+	loaded := map[string]bool{}
+	for _, name := range loaded_programs {
+		loaded[name] = true
+	}
+	for name := range c.ports {
+		if !loaded[name] {
+			delete(c.ports, name)
+		}
+	}
 	return loaded_programs
 
 }
 
+// get the expressions to evaluate the values of the program instance
+func (c *Config) programInstanceEnvs(tmpl *programTemplate, processNum int) *StringExpression {
+	return NewStringExpression("program_name", tmpl.programName,
+		"process_num", fmt.Sprintf("%d", processNum),
+		"group_name", c.ProgramGroup.GetGroup(tmpl.programName, tmpl.programName),
+		"here", c.GetConfigFileDir())
+}
+
+// create the entry of the processNum instance of the program, return the process name.
+func (c *Config) createProgramInstance(tmpl *programTemplate, processNum int) (string, error) {
+	envs := c.programInstanceEnvs(tmpl, processNum)
+	procName, err := envs.Eval(tmpl.procName)
+	if err != nil {
+		return "", errors.As(err, tmpl.programName)
+	}
+	port, err := c.allocatePort(tmpl, procName, processNum)
+	if err != nil {
+		log.WithFields(log.Fields{"program": procName}).Error("fail to allocate the port:", err)
+		return "", errors.As(err)
+	}
+	if port > 0 {
+		envs.Add("port", strconv.Itoa(port))
+	}
+	cmd, err := envs.Eval(tmpl.command)
+	if err != nil {
+		return "", errors.As(err, tmpl.programName)
+	}
+
+	section := tmpl.section
+	if port > 0 {
+		section.NewKey("port", strconv.Itoa(port))
+	} else {
+		section.DeleteKey("port")
+	}
+	section.NewKey("command", cmd)
+	section.NewKey("process_name", procName)
+	section.NewKey("numprocs_start", fmt.Sprintf("%d", (processNum-1)))
+	section.NewKey("process_num", fmt.Sprintf("%d", processNum))
+	entry := c.createEntry(procName, c.GetConfigFileDir())
+	entry.parse(section)
+	entry.Name = tmpl.prefix + procName
+	entry.Program = tmpl.programName
+	entry.Group = c.ProgramGroup.GetGroup(tmpl.programName, tmpl.programName)
+	if port == 0 {
+		delete(entry.keyValues, "port")
+	}
+	return procName, nil
+}
+
+// ScaleProgram change the numprocs of the program at runtime, the numprocs is kept
+// when the configuration is loaded again.
+//
+// Return the names of the added processes and the removed processes, the removed
+// processes are from the highest number down.
+func (c *Config) ScaleProgram(programName string, numprocs int) (added []string, removed []string, err error) {
+	tmpl, ok := c.programTemplates[programName]
+	if !ok || tmpl.prefix != "program:" {
+		return nil, nil, errors.New("program not found").As(programName)
+	}
+	if numprocs < 0 {
+		return nil, nil, errors.New("invalid numprocs").As(programName, numprocs)
+	}
+	if numprocs > 1 && strings.Index(tmpl.procName, "%(process_num)") == -1 {
+		return nil, nil, errors.New("no process_num in process name").As(programName, tmpl.procName)
+	}
+
+	for i := tmpl.numprocs + 1; i <= numprocs; i++ {
+		procName, err := c.createProgramInstance(tmpl, i)
+		if err != nil {
+			return added, removed, errors.As(err)
+		}
+		added = append(added, procName)
+		tmpl.numprocs = i
+	}
+	for i := tmpl.numprocs; i > numprocs; i-- {
+		procName, err := c.programInstanceEnvs(tmpl, i).Eval(tmpl.procName)
+		if err != nil {
+			return added, removed, errors.As(err, programName)
+		}
+		c.RemoveProgram(procName)
+		removed = append(removed, procName)
+		tmpl.numprocs = i - 1
+	}
+	c.numprocs[programName] = scaledNumprocs{numprocs: numprocs, confNumprocs: tmpl.confNumprocs}
+	return added, removed, nil
+}
+
+// GetConfigNumprocs get the numprocs of the program in the configuration file
+func (c *Config) GetConfigNumprocs(programName string) (int, bool) {
+	tmpl, ok := c.programTemplates[programName]
+	if !ok {
+		return 0, false
+	}
+	return tmpl.confNumprocs, true
+}
+
+// IsScaled return true if the numprocs of the program is scaled at runtime
+func (c *Config) IsScaled(programName string) bool {
+	_, ok := c.numprocs[programName]
+	return ok
+}
+
 func (c *Config) String() string {
 	buf := bytes.NewBuffer(make([]byte, 0))
 	fmt.Fprintf(buf, "configFile:%s\n", c.configFile)
@@ -618,5 +971,6 @@ func (c *Config) String() string {
 
 func (c *Config) RemoveProgram(programName string) {
 	delete(c.entries, programName)
+	delete(c.ports, programName)
 	c.ProgramGroup.Remove(programName)
 }
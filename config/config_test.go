@@ -98,6 +98,21 @@ func TestGetEnvValueFromConfig(t *testing.T) {
 		t.Error("Fail to get env value")
 	}
 
+	config, _ = parse([]byte("[program:test]\na=A=\"a,b\",B= \"c d\" ,C=e"))
+	entry = config.GetProgram("test")
+	envs = entry.GetEnv("a")
+	if len(envs) != 3 || envs[0] != "A=a,b" || envs[1] != "B=c d" || envs[2] != "C=e" {
+		t.Errorf("Fail to get env value with comma: %q", envs)
+	}
+
+	// the quote and the backslash are not special in the unquoted value
+	config, _ = parse([]byte("[program:test]\na=PATH=C:\\tools,MSG=don't,B=1"))
+	entry = config.GetProgram("test")
+	envs = entry.GetEnv("a")
+	if len(envs) != 3 || envs[0] != "PATH=C:\\tools" || envs[1] != "MSG=don't" || envs[2] != "B=1" {
+		t.Errorf("Fail to get env value with backslash and quote: %q", envs)
+	}
+
 }
 
 func TestGetBytesFromConfig(t *testing.T) {
@@ -181,3 +196,89 @@ func TestConfigWithInclude(t *testing.T) {
 	}
 
 }
+
+func TestCheckConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tmp")
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "file1")
+
+	ioutil.WriteFile(confFile, []byte("[program:cat]\ncommand=pwd\n[include]\nfiles=conf.d/*.conf"), os.ModePerm)
+	os.Mkdir(filepath.Join(dir, "conf.d"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "conf.d", "ls.conf"), []byte("[program:ls]\ncommand=ls\n"), os.ModePerm)
+	config := NewConfig(confFile)
+	if err := config.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if !config.IsConfigFile(confFile) || !config.IsConfigFile(filepath.Join(dir, "conf.d", "new.conf")) ||
+		config.IsConfigFile(filepath.Join(dir, "conf.d", "new.txt")) || config.IsConfigFile(filepath.Join(dir, "new.conf")) {
+		t.Error("Fail to match the config files")
+	}
+	if dirs := config.GetConfigDirs(); len(dirs) != 2 || dirs[1] != filepath.Join(dir, "conf.d") {
+		t.Errorf("Fail to get the config dirs: %v", dirs)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "conf.d", "bad.conf"), []byte("[program:bad\ncommand=ls\n"), os.ModePerm)
+	if err := config.Check(); err == nil {
+		t.Error("expect the bad included file is rejected")
+	}
+	os.Remove(filepath.Join(dir, "conf.d", "bad.conf"))
+	ioutil.WriteFile(filepath.Join(dir, "conf.d", "bad.conf"), []byte("[program:bad]\nautostart=true\n"), os.ModePerm)
+	if err := config.Check(); err == nil {
+		t.Error("expect the program without command is rejected")
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	config1, _ := parse([]byte("[program:test]\ncommand=/bin/ls\na=1\nb=2\n"))
+	config2, _ := parse([]byte("[program:test]\ncommand=/bin/ls\na=3\nc=4\n"))
+	keys := config1.GetProgram("test").ChangedKeys(config2.GetProgram("test"))
+	if fmt.Sprint(keys) != "[a b c]" {
+		t.Errorf("Fail to get the changed keys: %v", keys)
+	}
+	if len(config1.GetProgram("test").ChangedKeys(config1.GetProgram("test"))) != 0 {
+		t.Error("Fail to get the changed keys of same entry")
+	}
+}
+
+func TestNumprocsProgram(t *testing.T) {
+	config, err := parse([]byte("[program:worker]\ncommand=/bin/worker %(process_num)s\nprocess_name=%(program_name)s_%(process_num)s\nnumprocs=2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"worker_1", "worker_2"} {
+		entry := config.GetProgram(name)
+		if entry == nil {
+			t.Fatalf("Fail to parse the numprocs program %s", name)
+		}
+		if entry.Program != "worker" || entry.GetString("command", "") != "/bin/worker "+name[len("worker_"):] {
+			t.Errorf("Fail to parse the numprocs program %s: %s, %s", name, entry.Program, entry.GetString("command", ""))
+		}
+	}
+}
+
+func TestScaleProgram(t *testing.T) {
+	config, err := parse([]byte("[program:worker]\ncommand=/bin/worker %(process_num)s\nprocess_name=%(program_name)s_%(process_num)s\nnumprocs=2\n[program:single]\ncommand=/bin/single\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, removed, err := config.ScaleProgram("worker", 4)
+	if err != nil || fmt.Sprint(added) != "[worker_3 worker_4]" || len(removed) != 0 {
+		t.Fatalf("Fail to scale out the program: %v %v %v", added, removed, err)
+	}
+	if entry := config.GetProgram("worker_4"); entry == nil || entry.GetString("command", "") != "/bin/worker 4" {
+		t.Error("Fail to create the scaled process")
+	}
+	added, removed, err = config.ScaleProgram("worker", 1)
+	if err != nil || len(added) != 0 || fmt.Sprint(removed) != "[worker_4 worker_3 worker_2]" {
+		t.Fatalf("Fail to scale in the program: %v %v %v", added, removed, err)
+	}
+	if config.GetProgram("worker_2") != nil || config.GetProgram("worker_1") == nil {
+		t.Error("Fail to remove the scaled process")
+	}
+	if _, _, err := config.ScaleProgram("single", 2); err == nil {
+		t.Error("expect the program without process_num can't be scaled")
+	}
+	if _, _, err := config.ScaleProgram("none", 2); err == nil {
+		t.Error("expect the program is not found")
+	}
+}
@@ -0,0 +1,410 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/gwaylib/errors"
+)
+
+// ParseDotenv parse the KEY=VALUE entries of a dotenv file.
+//
+// The supported syntax:
+//
+//  # comment
+//  export KEY=value
+//  KEY=value # inline comment
+//  KEY='literal $value'
+//  KEY="escaped \"value\"\n with ${OTHER:-default} expansion"
+//  KEY="multi
+//  line value"
+//
+// Variables are looked up from the entries parsed before, then from lookup.
+// The result is in the order of the first appearance of the key, a key defined
+// again overrides the former value.
+func ParseDotenv(data []byte, lookup func(string) (string, bool)) ([]string, error) {
+	p := &dotenvParser{
+		src:    []rune(strings.Replace(string(data), "\r\n", "\n", -1)),
+		line:   1,
+		values: make(map[string]string),
+		lookup: lookup,
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(p.keys))
+	for _, k := range p.keys {
+		result = append(result, k+"="+p.values[k])
+	}
+	return result, nil
+}
+
+// LoadDotenvFiles load the dotenv files in order.
+//
+// A file name starts with '-' is optional and ignored if it does not exist.
+// Variables of the latter files can refer to the former ones.
+func LoadDotenvFiles(files []string, lookup func(string) (string, bool)) ([]string, error) {
+	loaded := make(map[string]string)
+	chainLookup := func(key string) (string, bool) {
+		if v, ok := loaded[key]; ok {
+			return v, true
+		}
+		if lookup != nil {
+			return lookup(key)
+		}
+		return "", false
+	}
+
+	result := make([]string, 0)
+	for _, f := range files {
+		optional := strings.HasPrefix(f, "-")
+		if optional {
+			f = f[1:]
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			if optional && os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.As(err, f)
+		}
+		env, err := ParseDotenv(data, chainLookup)
+		if err != nil {
+			return nil, errors.As(err, f)
+		}
+		for _, kv := range env {
+			pos := strings.Index(kv, "=")
+			loaded[kv[:pos]] = kv[pos+1:]
+		}
+		result = append(result, env...)
+	}
+	return result, nil
+}
+
+// GetEnvFiles get the value of key as a list of dotenv files and load them.
+//
+//  env_file = %(here)s/common.env, -/etc/supd/%(program_name)s.env, "-/etc/supd/my app.env"
+//
+// The files are separated by ',' or spaces, a path with them is quoted by '"'.
+// A relative path is relative to the directory of the configuration.
+func (c *ConfigEntry) GetEnvFiles(key string) ([]string, error) {
+	files := splitEnvFiles(c.GetStringExpression(key, ""))
+	for i, f := range files {
+		prefix := ""
+		if strings.HasPrefix(f, "-") {
+			prefix, f = "-", f[1:]
+		}
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(c.ConfigDir, f)
+		}
+		files[i] = prefix + f
+	}
+	return LoadDotenvFiles(files, os.LookupEnv)
+}
+
+// split the files of env_file by ',' or spaces which are not quoted by '"', the quotes are removed.
+func splitEnvFiles(value string) []string {
+	files := []string{}
+	var buf strings.Builder
+	quoted := false
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ',' || unicode.IsSpace(r)):
+			if buf.Len() > 0 {
+				files = append(files, buf.String())
+				buf.Reset()
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	if buf.Len() > 0 {
+		files = append(files, buf.String())
+	}
+	return files
+}
+
+type dotenvParser struct {
+	src    []rune
+	pos    int
+	line   int
+	keys   []string
+	values map[string]string
+	lookup func(string) (string, bool)
+}
+
+func (p *dotenvParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("dotenv line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *dotenvParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *dotenvParser) next() rune {
+	r := p.src[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func (p *dotenvParser) skipBlank() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func (p *dotenvParser) set(key, value string) {
+	if _, ok := p.values[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.values[key] = value
+}
+
+func (p *dotenvParser) get(key string) (string, bool) {
+	if v, ok := p.values[key]; ok {
+		return v, true
+	}
+	if p.lookup != nil {
+		return p.lookup(key)
+	}
+	return "", false
+}
+
+func (p *dotenvParser) parse() error {
+	for !p.eof() {
+		p.skipBlank()
+		if p.eof() {
+			break
+		}
+		switch p.peek() {
+		case '\n':
+			p.next()
+			continue
+		case '#':
+			p.skipLine()
+			continue
+		}
+
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		p.set(key, value)
+	}
+	return nil
+}
+
+func (p *dotenvParser) parseKey() (string, error) {
+	start := p.pos
+	for !p.eof() && isDotenvKeyRune(p.peek()) {
+		p.next()
+	}
+	key := string(p.src[start:p.pos])
+	if key == "export" && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipBlank()
+		return p.parseKey()
+	}
+	if len(key) == 0 || unicode.IsDigit(rune(key[0])) {
+		return "", p.errorf("invalid variable name")
+	}
+	p.skipBlank()
+	if p.eof() || p.next() != '=' {
+		return "", p.errorf("missing '=' after %s", key)
+	}
+	return key, nil
+}
+
+func isDotenvKeyRune(r rune) bool {
+	return r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func (p *dotenvParser) parseValue() (string, error) {
+	p.skipBlank()
+	var value string
+	var err error
+	switch p.peek() {
+	case '\'':
+		value, err = p.parseSingleQuoted()
+	case '"':
+		value, err = p.parseDoubleQuoted()
+	default:
+		return p.parseUnquoted()
+	}
+	if err != nil {
+		return "", err
+	}
+	// only comment is allowed after the quoted value
+	p.skipBlank()
+	if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+		return "", p.errorf("unexpected character %q after quoted value", p.peek())
+	}
+	p.skipLine()
+	return value, nil
+}
+
+func (p *dotenvParser) parseSingleQuoted() (string, error) {
+	line := p.line
+	p.next()
+	start := p.pos
+	for !p.eof() {
+		if p.next() == '\'' {
+			return string(p.src[start : p.pos-1]), nil
+		}
+	}
+	p.line = line
+	return "", p.errorf("unterminated single quoted value")
+}
+
+func (p *dotenvParser) parseDoubleQuoted() (string, error) {
+	line := p.line
+	p.next()
+	var buf strings.Builder
+	for !p.eof() {
+		r := p.next()
+		switch r {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			if p.eof() {
+				break
+			}
+			switch e := p.next(); e {
+			case 'n':
+				buf.WriteRune('\n')
+			case 'r':
+				buf.WriteRune('\r')
+			case 't':
+				buf.WriteRune('\t')
+			case '\n':
+				// line continuation
+			default:
+				buf.WriteRune(e)
+			}
+		case '$':
+			v, err := p.parseVariable()
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(v)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	p.line = line
+	return "", p.errorf("unterminated double quoted value")
+}
+
+func (p *dotenvParser) parseUnquoted() (string, error) {
+	var buf strings.Builder
+	for !p.eof() && p.peek() != '\n' {
+		r := p.next()
+		switch {
+		case r == '#' && (buf.Len() == 0 || unicode.IsSpace(p.src[p.pos-2])):
+			// inline comment
+			p.skipLine()
+			return strings.TrimSpace(buf.String()), nil
+		case r == '\\' && p.peek() == '$':
+			buf.WriteRune(p.next())
+		case r == '$':
+			v, err := p.parseVariable()
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(v)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	p.skipLine()
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// parse the variable after '$' and return the expanded value.
+//
+//  $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}
+func (p *dotenvParser) parseVariable() (string, error) {
+	if p.peek() != '{' {
+		start := p.pos
+		for !p.eof() && (p.peek() == '_' || unicode.IsLetter(p.peek()) || unicode.IsDigit(p.peek())) {
+			p.next()
+		}
+		if start == p.pos {
+			return "$", nil
+		}
+		v, _ := p.get(string(p.src[start:p.pos]))
+		return v, nil
+	}
+
+	p.next()
+	start := p.pos
+	for !p.eof() && p.peek() != '}' && p.peek() != ':' && p.peek() != '-' {
+		p.next()
+	}
+	name := string(p.src[start:p.pos])
+	if p.eof() {
+		return "", p.errorf("unterminated variable ${%s", name)
+	}
+	value, ok := p.get(name)
+	if p.peek() == '}' {
+		p.next()
+		return value, nil
+	}
+
+	// default value
+	emptyAsUnset := p.peek() == ':'
+	if emptyAsUnset {
+		p.next()
+		if p.eof() || p.peek() != '-' {
+			return "", p.errorf("unsupported expression in ${%s", name)
+		}
+	}
+	p.next()
+	var def strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated variable ${%s", name)
+		}
+		r := p.next()
+		if r == '}' {
+			break
+		}
+		if r == '$' {
+			v, err := p.parseVariable()
+			if err != nil {
+				return "", err
+			}
+			def.WriteString(v)
+			continue
+		}
+		def.WriteRune(r)
+	}
+	if !ok || (emptyAsUnset && value == "") {
+		return def.String(), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	data := []byte(`# comment
+export A=1
+B = hello world # inline comment
+C='single $A "quoted"'
+D="double \"$A\"\tend"
+E="multi
+line"
+F=${UNSET:-default}
+G=${A:-default}
+H=${EMPTY:-empty}${EMPTY-set}
+I=pass\$word#1
+A=2
+`)
+	lookup := func(key string) (string, bool) {
+		if key == "EMPTY" {
+			return "", true
+		}
+		return "", false
+	}
+	env, err := ParseDotenv(data, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"A=2",
+		"B=hello world",
+		`C=single $A "quoted"`,
+		"D=double \"1\"\tend",
+		"E=multi\nline",
+		"F=default",
+		"G=1",
+		"H=empty",
+		"I=pass$word#1",
+	}
+	if len(env) != len(expect) {
+		t.Fatalf("expect %d entries, but got %d: %q", len(expect), len(env), env)
+	}
+	for i := range expect {
+		if env[i] != expect[i] {
+			t.Errorf("expect %q, but got %q", expect[i], env[i])
+		}
+	}
+}
+
+func TestParseDotenvError(t *testing.T) {
+	for _, data := range []string{
+		"A",
+		"1A=b",
+		"A=\"unterminated",
+		"A='a' b",
+		"A=${B",
+	} {
+		if _, err := ParseDotenv([]byte(data), nil); err == nil {
+			t.Errorf("expect error of %q", data)
+		}
+	}
+}
+
+func TestLoadDotenvFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f1 := filepath.Join(dir, "1.env")
+	f2 := filepath.Join(dir, "2.env")
+	ioutil.WriteFile(f1, []byte("A=1\n"), 0644)
+	ioutil.WriteFile(f2, []byte("B=${A}2\n"), 0644)
+
+	env, err := LoadDotenvFiles([]string{f1, "-" + filepath.Join(dir, "missing.env"), f2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 2 || env[0] != "A=1" || env[1] != "B=12" {
+		t.Errorf("fail to load env files: %q", env)
+	}
+
+	if _, err := LoadDotenvFiles([]string{filepath.Join(dir, "missing.env")}, nil); err == nil {
+		t.Error("expect error of the missing file")
+	}
+}
+
+func TestSplitEnvFiles(t *testing.T) {
+	files := splitEnvFiles(`a.env, -/etc/b.env "/etc/my app.env","-c d.env"`)
+	if len(files) != 4 || files[0] != "a.env" || files[1] != "-/etc/b.env" || files[2] != "/etc/my app.env" || files[3] != "-c d.env" {
+		t.Errorf("fail to split env files: %q", files)
+	}
+}
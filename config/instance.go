@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gwaylib/errors"
+)
+
+// the separator of the template and the instance, e.g. worker@tenant42 is an instance of [program:worker@]
+const INSTANCE_SEP = "@"
+
+// IsInstanceName return true if the name is an instance of a template, e.g. worker@tenant42
+func IsInstanceName(name string) bool {
+	pos := strings.Index(name, INSTANCE_SEP)
+	return pos > 0 && pos < len(name)-1
+}
+
+// split the instance name to the template and the instance
+func splitInstanceName(name string) (template string, instance string, err error) {
+	if !IsInstanceName(name) {
+		return "", "", errors.New("invalid instance name").As(name)
+	}
+	pos := strings.Index(name, INSTANCE_SEP)
+	template, instance = name[:pos], name[pos+1:]
+	for _, r := range instance {
+		if unicode.IsSpace(r) || r == ':' || r == '/' || r == '%' {
+			return "", "", errors.New("invalid instance name").As(name)
+		}
+	}
+	return template, instance, nil
+}
+
+// CreateInstance create the program entry of the instance from the [program:<template>@] section,
+// the %(instance)s in the values is replaced by the instance.
+//
+// The instance is created again when the configuration is loaded, until it is removed by RemoveInstance.
+func (c *Config) CreateInstance(name string) (*ConfigEntry, error) {
+	template, instance, err := splitInstanceName(name)
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	section, ok := c.instanceTemplates[template]
+	if !ok {
+		return nil, errors.New("template not found").As(name)
+	}
+
+	entry := c.createEntry(name, c.GetConfigFileDir())
+	entry.parse(section)
+	for key, value := range entry.keyValues {
+		entry.keyValues[key] = strings.Replace(value, "%(instance)s", instance, -1)
+	}
+	entry.keyValues["instance"] = instance
+	entry.Name = "program:" + name
+	entry.Program = template + INSTANCE_SEP
+	entry.Group = c.ProgramGroup.GetGroup(name, name)
+	c.instances[name] = true
+	return entry, nil
+}
+
+// RemoveInstance remove the instance which is created by CreateInstance.
+func (c *Config) RemoveInstance(name string) {
+	delete(c.instances, name)
+	c.RemoveProgram(name)
+}
+
+// GetInstances get the names of the instances created from the templates.
+func (c *Config) GetInstances() []string {
+	result := []string{}
+	for name := range c.instances {
+		result = append(result, name)
+	}
+	return result
+}
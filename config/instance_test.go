@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestCreateInstance(t *testing.T) {
+	config, err := parse([]byte("[program:worker@]\ncommand=/bin/worker --tenant %(instance)s\nstdout_logfile=/var/log/worker-%(instance)s.log\nenvironment=TENANT=%(instance)s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.GetPrograms()) != 0 {
+		t.Error("the template should not be a program")
+	}
+	if IsInstanceName("worker@") || IsInstanceName("@abc") || !IsInstanceName("worker@t42") {
+		t.Error("Fail to check the instance name")
+	}
+	entry, err := config.CreateInstance("worker@t42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.GetProgramName() != "worker@t42" || entry.GetString("command", "") != "/bin/worker --tenant t42" ||
+		entry.GetString("stdout_logfile", "") != "/var/log/worker-t42.log" || entry.GetEnv("environment")[0] != "TENANT=t42" {
+		t.Errorf("Fail to create the instance: %s", entry.String())
+	}
+	if config.GetProgram("worker@t42") == nil {
+		t.Error("Fail to get the instance")
+	}
+	if _, err := config.CreateInstance("other@t42"); err == nil {
+		t.Error("expect the template is not found")
+	}
+	if _, err := config.CreateInstance("worker@t 42"); err == nil {
+		t.Error("expect the instance name is invalid")
+	}
+
+	config.RemoveInstance("worker@t42")
+	if config.GetProgram("worker@t42") != nil || len(config.GetInstances()) != 0 {
+		t.Error("Fail to remove the instance")
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gwaylib/errors"
+	"gopkg.in/go-ini/ini.v1"
+)
+
+// get the range of the ports allocated to the instances of the program, ok is false if it is not configured.
+//
+//  port_range=9000-9099
+//  port_base=9000        - the range is from the base to 65535
+func getPortRange(section *ini.Section) (low int, high int, ok bool, err error) {
+	if value := strings.TrimSpace(section.Key("port_range").Value()); value != "" {
+		bounds := strings.SplitN(value, "-", 2)
+		if len(bounds) != 2 {
+			return 0, 0, false, errors.New("invalid port_range").As(value)
+		}
+		low, err1 := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		high, err2 := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err1 != nil || err2 != nil || low <= 0 || high > 65535 || low > high {
+			return 0, 0, false, errors.New("invalid port_range").As(value)
+		}
+		return low, high, true, nil
+	}
+	if value := strings.TrimSpace(section.Key("port_base").Value()); value != "" {
+		low, err := strconv.Atoi(value)
+		if err != nil || low <= 0 || low > 65535 {
+			return 0, 0, false, errors.New("invalid port_base").As(value)
+		}
+		return low, 65535, true, nil
+	}
+	return 0, 0, false, nil
+}
+
+// true if the tcp port can be listened on
+func isPortFree(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// allocate the port of the process instance, 0 if the program has no port range.
+//
+// The allocated port is kept for the process name, so it is stable across the reloads and restarts.
+// The new port is the free one from the base + process_num - 1, which is not allocated to other instances.
+func (c *Config) allocatePort(tmpl *programTemplate, procName string, processNum int) (int, error) {
+	low, high, ok, err := getPortRange(tmpl.section)
+	if err != nil {
+		return 0, errors.As(err, tmpl.programName)
+	}
+	if !ok {
+		delete(c.ports, procName)
+		return 0, nil
+	}
+	if port, ok := c.ports[procName]; ok && port >= low && port <= high {
+		return port, nil
+	}
+	reserved := map[int]bool{}
+	for name, port := range c.ports {
+		if name != procName {
+			reserved[port] = true
+		}
+	}
+	size := high - low + 1
+	for i := 0; i < size; i++ {
+		port := low + (processNum-1+i)%size
+		if reserved[port] || !isPortFree(port) {
+			continue
+		}
+		c.ports[procName] = port
+		return port, nil
+	}
+	return 0, errors.New("no free port in the range").As(tmpl.programName, low, high)
+}
+
+// GetPorts get the ports allocated to the process instances
+func (c *Config) GetPorts() map[string]int {
+	ports := map[string]int{}
+	for name, port := range c.ports {
+		ports[name] = port
+	}
+	return ports
+}
+
+// SetPorts restore the ports allocated before, e.g. by the previous supd, and create
+// the instances with their ports again. The ports of the instances not created yet are
+// reserved for them.
+func (c *Config) SetPorts(ports map[string]int) {
+	for name, port := range ports {
+		c.ports[name] = port
+	}
+	// the instances allocated newly by the last loading may conflict with the restored ones
+	for name, port := range c.ports {
+		if restored, ok := ports[name]; ok && restored == port {
+			continue
+		}
+		for other, otherPort := range ports {
+			if other != name && otherPort == port {
+				delete(c.ports, name)
+				break
+			}
+		}
+	}
+	for _, tmpl := range c.programTemplates {
+		if _, _, ok, _ := getPortRange(tmpl.section); !ok {
+			continue
+		}
+		for i := 1; i <= tmpl.numprocs; i++ {
+			c.createProgramInstance(tmpl, i)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"net"
+	"os"
+	"testing"
+)
+
+func TestAllocatePort(t *testing.T) {
+	// the port of the second instance is in use
+	ln, err := net.Listen("tcp", ":39401")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	fileName, err := saveToTmpFile([]byte(`[program:web]
+command=/bin/web --port %(port)s
+process_name=web_%(process_num)s
+numprocs=3
+port_range=39400-39409
+environment=LISTEN=:%(port)s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fileName)
+	config := NewConfig(fileName)
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]int{"web_1": 39400, "web_2": 39402, "web_3": 39403}
+	check := func() {
+		ports := config.GetPorts()
+		for name, port := range expect {
+			if ports[name] != port {
+				t.Fatalf("expect the port of %s is %d, but got %v", name, port, ports)
+			}
+		}
+		if len(ports) != len(expect) {
+			t.Fatalf("unexpected ports: %v", ports)
+		}
+	}
+	check()
+	entry := config.GetProgram("web_2")
+	if entry.GetString("command", "") != "/bin/web --port 39402" || entry.GetEnv("environment")[0] != "LISTEN=:39402" {
+		t.Fatalf("unexpected entry: %s", entry.String())
+	}
+
+	// the ports are stable across the reloads
+	ln.Close()
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	check()
+
+	// the ports are restored and reserved for the instances which are not created yet
+	config.SetPorts(map[string]int{"web_1": 39405, "web_4": 39400})
+	expect = map[string]int{"web_1": 39405, "web_2": 39402, "web_3": 39403, "web_4": 39400}
+	check()
+	if config.GetProgram("web_1").GetString("command", "") != "/bin/web --port 39405" {
+		t.Fatalf("expect the restored port is used, but got %s", config.GetProgram("web_1").String())
+	}
+	if _, _, err := config.ScaleProgram("web", 4); err != nil {
+		t.Fatal(err)
+	}
+	check()
+
+	// the ports of the removed instances are released
+	if _, _, err := config.ScaleProgram("web", 2); err != nil {
+		t.Fatal(err)
+	}
+	expect = map[string]int{"web_1": 39405, "web_2": 39402}
+	check()
+}
+
+func TestPortRange(t *testing.T) {
+	config, err := parse([]byte("[program:a]\ncommand=/bin/a\nport_range=9100-9000\n\n[program:b]\ncommand=/bin/b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.GetProgram("a") != nil {
+		t.Error("expect the program with invalid port_range is skipped")
+	}
+	if entry := config.GetProgram("b"); entry == nil || entry.GetString("port", "") != "" {
+		t.Error("expect the program without port_range has no port")
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gwaylib/errors"
+)
+
+const SECRET_SCHEME = "secret://"
+
+// SecretProvider resolve the secret of a reference, the reference is the part
+// after "secret://<provider>/", for example:
+//
+//  secret://file/etc/supd/secrets/db  -> the "file" provider resolves "etc/supd/secrets/db"
+//  secret://env/DB_PASS               -> the "env" provider resolves "DB_PASS"
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretProviderFunc adapt a function to the SecretProvider.
+type SecretProviderFunc func(ref string) (string, error)
+
+func (f SecretProviderFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	secretProvidersLock sync.RWMutex
+	secretProviders     = map[string]SecretProvider{
+		"file": SecretProviderFunc(resolveFileSecret),
+		"env":  SecretProviderFunc(resolveEnvSecret),
+	}
+)
+
+// RegisterSecretProvider register or replace the provider of the name.
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretProvidersLock.Lock()
+	defer secretProvidersLock.Unlock()
+	secretProviders[name] = provider
+}
+
+// read the secret from an absolute file path, the tailing newline is removed.
+func resolveFileSecret(ref string) (string, error) {
+	data, err := ioutil.ReadFile("/" + strings.TrimPrefix(ref, "/"))
+	if err != nil {
+		return "", errors.As(err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// read the secret from the environment of supd.
+func resolveEnvSecret(ref string) (string, error) {
+	val, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", errors.New("environment not found").As(ref)
+	}
+	return val, nil
+}
+
+// IsSecretRef return true if the value is a secret reference.
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), SECRET_SCHEME)
+}
+
+// ResolveSecret resolve the value if it is a secret reference, or return the value.
+//
+// The error does not contain the secret.
+func ResolveSecret(value string) (string, error) {
+	if !IsSecretRef(value) {
+		return value, nil
+	}
+	ref := strings.TrimSpace(value)[len(SECRET_SCHEME):]
+	name, path := ref, ""
+	if pos := strings.Index(ref, "/"); pos != -1 {
+		name, path = ref[:pos], ref[pos+1:]
+	}
+
+	secretProvidersLock.RLock()
+	provider, ok := secretProviders[name]
+	secretProvidersLock.RUnlock()
+	if !ok {
+		return "", errors.New("secret provider not found").As(name)
+	}
+	secret, err := provider.Resolve(path)
+	if err != nil {
+		return "", errors.As(err, name, path)
+	}
+	return secret, nil
+}
+
+// ResolveSecretEnv resolve the secret references in the KEY=VALUE list.
+func ResolveSecretEnv(env []string) ([]string, error) {
+	result := make([]string, len(env))
+	for i, kv := range env {
+		pos := strings.Index(kv, "=")
+		if pos == -1 || !IsSecretRef(kv[pos+1:]) {
+			result[i] = kv
+			continue
+		}
+		secret, err := ResolveSecret(kv[pos+1:])
+		if err != nil {
+			return nil, errors.As(err, kv[:pos])
+		}
+		result[i] = kv[:pos+1] + secret
+	}
+	return result, nil
+}
+
+var secretRefRegexp = regexp.MustCompile(`(secret://[^/\s,"']*)[^\s,"']*`)
+
+// RedactSecret hide the path of the secret references in the value, only the provider is kept.
+//
+//  DB_PASS=secret://file/etc/supd/secrets/db -> DB_PASS=secret://file/***
+func RedactSecret(value string) string {
+	return secretRefRegexp.ReplaceAllString(value, "${1}/***")
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecretEnv(t *testing.T) {
+	f, err := saveToTmpFile([]byte("file-secret\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f)
+	os.Setenv("SUPD_TEST_SECRET", "env-secret")
+	defer os.Unsetenv("SUPD_TEST_SECRET")
+	RegisterSecretProvider("test", SecretProviderFunc(func(ref string) (string, error) {
+		return strings.ToUpper(ref), nil
+	}))
+
+	env, err := ResolveSecretEnv([]string{
+		"A=plain",
+		"B=secret://file" + f,
+		"C=secret://env/SUPD_TEST_SECRET",
+		"D=secret://test/abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"A=plain", "B=file-secret", "C=env-secret", "D=ABC"}
+	for i := range expect {
+		if env[i] != expect[i] {
+			t.Errorf("expect %q, but got %q", expect[i], env[i])
+		}
+	}
+
+	if _, err := ResolveSecretEnv([]string{"A=secret://unknown/a"}); err == nil {
+		t.Error("expect error of unknown provider")
+	}
+	if _, err := ResolveSecretEnv([]string{"A=secret://env/SUPD_TEST_SECRET_NOT_FOUND"}); err == nil {
+		t.Error("expect error of missing environment")
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	config, _ := parse([]byte("[program:test]\nenvironment=A=1,DB_PASS=secret://file/etc/supd/secrets/db"))
+	entry := config.GetProgram("test")
+	s := entry.String()
+	if strings.Contains(s, "/etc/supd/secrets/db") || !strings.Contains(s, "DB_PASS=secret://file/***") {
+		t.Errorf("fail to redact the secret: %s", s)
+	}
+	if strings.Contains(config.String(), "/etc/supd/secrets/db") {
+		t.Error("fail to redact the secret of config")
+	}
+}
+
+func TestResolveFileSecretNotFound(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "secret")
+	defer os.RemoveAll(dir)
+	if _, err := ResolveSecret("secret://file" + dir + "/missing"); err == nil {
+		t.Error("expect error of missing file")
+	}
+}
@@ -34,21 +34,66 @@ pidfile=%(here)s/supervisord.pid
 #strip_ansi=not support
 #environment=not support
 identifier=supervisor
+# keep the children running when supd exits by signal or ctl shutdown, and adopt them at next start,
+# the children are kept and adopted too when the whole supd is reloaded by ctl.
+# the output of the children is forwarded by the fifo files in the dir of statefile.
+# the output is buffered by the fifo while supd is down, the child blocks on writing if the buffer (64KB) is full.
+# if supd is managed by systemd, KillMode=process is needed.
+adopt_children=false
+# the runtime state (stopped by user, ctl setenv, adoptable children) kept across restarts
+statefile=%(here)s/supd.state
+# reload the config like ctl reload after the config file or the included files are changed,
+# the bad config is rejected and the running programs are kept.
+auto_reload=false
+auto_reload_delay=1
+# the bulk start, e.g. the autostart and ctl start all, starts the programs in the priority order,
+# at most start_concurrency programs are STARTING at the same time and they are started start_stagger apart.
+start_concurrency=0
+start_stagger=0s
+# keep the RUNNING programs in the file of the Prometheus file_sd format, it is updated on every
+# state change of the processes and also served on /discovery of the http server.
+discovery_file=%(here)s/supd-discovery.json
+# the host of the targets, it is the hostname by default
+discovery_host=
 
 [program:x]
 command=/bin/cat
 process_name=%(program_name)s
+# ctl scale x=N overrides the numprocs across the reloads and restarts until the numprocs here is changed.
 numprocs=1
 #numprocs_start=not support
+# allocate a free port to each instance from the range (or from port_base to 65535), it is %(port)s
+# in the command and the environment and exported as PORT, and kept for the instance across restarts.
+port_range=9000-9099
+#port_base=9000
+# the labels of the program in the discovery file, the program and group labels are added by supd.
+labels=env="prod",team="infra"
 autostart=true
 startsecs=3
 startretries=3
 autorestart=true
 exitcodes=0,2
+# stop the program by the command at first, the stopsignal is sent if it still runs after stopwaitsecs.
+stop_command=/usr/sbin/nginx -s quit
+# reload the program in place by ctl reload-program, the reload_command is used at first.
+# ctl reload restarts the running program if any key of the program is changed, the keys used by supd only,
+# e.g. autostart, priority and the hook timeouts, are applied without the restart. the older supd only
+# replaced the configuration and the change was applied at the next start.
+# the program is reloaded instead of restarted by ctl reload if only the reload_keys are changed.
+reload_signal=HUP
+reload_command=/usr/sbin/nginx -s reload
+reload_command_timeout=30
+reload_keys=environment,env_file
+# the signal names are HUP, INT, QUIT, KILL, USR1, USR2 and TERM, SIGHUP and hup are the same as HUP.
+# the unsupported name is skipped and reported, the older supd sent TERM for it.
 stopsignal=TERM
 stopwaitsecs=10
 stopasgroup=true
 killasgroup=true
+# ctl pause freezes the program by the freezer cgroup supd-<program> which supd creates under the cgroup of supd,
+# the program is moved into it after started. SIGSTOP and SIGCONT are sent to the process group if the cgroup
+# is not writable, e.g. supd is not root or the cgroup of supd is not delegated, or pause_cgroup is false.
+pause_cgroup=true
 user=user1
 redirect_stderr=false
 stdout_logfile=AUTO
@@ -61,18 +106,103 @@ stderr_logfile_maxbytes=50MB
 stderr_logfile_backups=10
 stderr_capture_maxbytes=0
 stderr_events_enabled=false
-environment=KEY="val",KEY2="val2"
+# the dotenv files are loaded before the environment, a missing file with the - prefix is ignored and
+# a path with spaces is quoted, e.g. "-%(here)s/my app.env". the quotes and escapes of the dotenv syntax
+# are only for the files, a value of the environment is quoted by " to contain ','.
+# the env_file is not the --env-file of supd, the file of --env-file is parsed as before, e.g. the empty values are skipped.
+env_file=%(here)s/x.env -%(here)s/x.local.env
+environment=KEY="val",KEY2="val2",DB_PASS=secret://file/etc/supd/secrets/db,API_KEY=secret://env/API_KEY
 directory=/tmp
+# the ports must be free before the spawn, the pid holding the port is reported as the spawnerr.
+# the directory and the executable of the command are checked too, the program is FATAL at once if they are missing.
+ports=8080,127.0.0.1:9090
 #umask=not support
 serverurl=AUTO
+# the hooks run with the environment, user and directory of the program, the output is in the program log.
+# the start is aborted and the program is in BACKOFF if the pre_start fails.
+pre_start=/bin/mkdir -p /tmp/x
+pre_start_timeout=30
+post_start=/bin/true
+post_start_timeout=30
+pre_stop=/bin/true
+pre_stop_timeout=30
+post_stop=/bin/true
+post_stop_timeout=30
+# the command forks the daemon and exits, the daemon is supervised by the pid in the pidfile
+# which is written in pidfile_timeout seconds after the start.
+daemonizes=false
+pidfile=/var/run/x.pid
+pidfile_timeout=10
+# the program speaks the sd_notify protocol by the NOTIFY_SOCKET, it is RUNNING after it sends READY=1
+# in notify_timeout seconds, and it is restarted if it does not send WATCHDOG=1 in watchdog_sec.
+# the STATUS= text is shown in the description of ctl status.
+# the socket is owned by the user of the program, the messages are accepted only from the main process,
+# the MAINPID and their process group like NotifyAccess=main of systemd.
+notify=false
+notify_timeout=90
+watchdog_sec=0
+# watch the executable of the program and the watch_paths, the watch_action is applied
+# after the changes are quiet for watch_delay seconds. watch_action=restart|reload|signal:HUP
+watch_paths=/etc/x/*.conf,conf/x.yaml
+watch_action=restart
+watch_delay=1
+# the program is silent if it writes nothing to stdout and stderr in output_timeout seconds while it is RUNNING,
+# it is restarted or the PROCESS_OUTPUT_TIMEOUT event is emitted. output_timeout_action=restart|event
+output_timeout=0
+output_timeout_action=restart
+# apply the action when a line of stdout or stderr matches the /regexp/, more rules are in the on_output.<name> keys.
+# the action is restart, signal:<SIG>, event (PROCESS_OUTPUT_MATCHED) or exec:<command> with SUPD_OUTPUT_LINE,
+# and the action of a rule is applied at most once in on_output_interval seconds.
+on_output=/OutOfMemoryError/ -> restart
+on_output.gc=/GC overhead limit/ -> exec:/usr/local/bin/notify
+on_output_interval=60
+on_output_exec_timeout=30
+# the program is skipped instead of started if any condition is not met, the reason is shown by ctl status.
+# the paths must exist or not exist with the ! prefix, the environments of supd must be set or set to the value,
+# the host name must match one of the patterns, and the condition_command must exit with 0.
+condition_path_exists=/data/x,!/etc/x.disabled
+condition_env=ROLE=web,!MAINTENANCE
+condition_host=web-*,api-?
+condition_command=/usr/bin/mountpoint -q /data
+condition_command_timeout=10
+# run the program only on the host holding the lock among the supd hosts, the others show STANDBY
+# and retry in every singleton_lock_refresh seconds. the lock is the flock of the file on the shared
+# filesystem by default, it is released when the holder exits or the NFS lease of the dead host expires,
+# the file is never removed by supd. the other backends are <scheme>://<target>.
+# the program with singleton_lock is not adopted by adopt_children, it is stopped with supd.
+singleton_lock=/shared/locks/x.lock
+singleton_lock_refresh=5
+
+# the template of the programs, "ctl start worker@tenant42" creates the program worker@tenant42
+# with %(instance)s replaced by tenant42, "ctl remove worker@tenant42" removes it.
+[program:worker@]
+command=/bin/worker --tenant %(instance)s
+stdout_logfile=%(here)s/worker-%(instance)s.log
+environment=TENANT="%(instance)s"
 
 [include]
 files=/an/absolute/filename.conf /an/absolute/*.conf foo.conf config??.conf
 
 [group:x]
 programs=bar,baz
+# the start limit of the programs in the group, it applies with the limit of [supervisord].
+start_concurrency=2
+start_stagger=1s
 priority=999
 
+# listen on one address and balance the connections across the RUNNING instances of the program
+# by their allocated ports, the instance is removed when it leaves RUNNING or fails the health check.
+[proxy:x]
+listen=0.0.0.0:8000
+program=x
+# tcp balances the connections, http balances the requests
+mode=tcp
+backend_host=127.0.0.1
+# tcp, http:<path> or empty for no check
+health_check=http:/healthz
+health_check_interval=5
+health_check_timeout=2
+
 [eventlistener:x]
 command=/bin/eventlistener
 process_name=%(program_name)s
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gwaycc/supd/config"
 	"github.com/gwaycc/supd/rpcclient"
@@ -39,6 +41,9 @@ type StopCommand struct {
 }
 
 type RestartCommand struct {
+	Rolling        bool          `long:"rolling" description:"restart the instances of a numprocs program or the members of a group in batches"`
+	MaxUnavailable int           `long:"max-unavailable" default:"1" description:"the number of processes restarted in a batch of the rolling restart"`
+	Pause          time.Duration `long:"pause" description:"the pause between the batches of the rolling restart, e.g. 10s"`
 }
 
 type ShutdownCommand struct {
@@ -47,6 +52,27 @@ type ShutdownCommand struct {
 type ReloadCommand struct {
 }
 
+type ReloadProgramCommand struct {
+}
+
+type ScaleCommand struct {
+}
+
+type RemoveCommand struct {
+}
+
+type DisableCommand struct {
+}
+
+type EnableCommand struct {
+}
+
+type PauseCommand struct {
+}
+
+type ResumeCommand struct {
+}
+
 type PidCommand struct {
 }
 
@@ -67,6 +93,13 @@ var stopCommand StopCommand
 var restartCommand RestartCommand
 var shutdownCommand ShutdownCommand
 var reloadCommand ReloadCommand
+var reloadProgramCommand ReloadProgramCommand
+var scaleCommand ScaleCommand
+var removeCommand RemoveCommand
+var disableCommand DisableCommand
+var enableCommand EnableCommand
+var pauseCommand PauseCommand
+var resumeCommand ResumeCommand
 var setEnvCommand SetEnvCommand
 var getEnvCommand GetEnvCommand
 var pidCommand PidCommand
@@ -157,6 +190,20 @@ func (x *CtlCommand) Execute(args []string) error {
 		x.shutdown(rpcc)
 	case "reload":
 		x.reload(rpcc)
+	case "reload-program":
+		x.reloadPrograms(rpcc, args[1:])
+	case "scale":
+		x.scale(rpcc, args[1:])
+	case "remove":
+		x.removeInstances(rpcc, args[1:])
+	case "disable":
+		x.disablePrograms(rpcc, args[1:])
+	case "enable":
+		x.enablePrograms(rpcc, args[1:])
+	case "pause":
+		x.pausePrograms(rpcc, args[1:])
+	case "resume":
+		x.resumePrograms(rpcc, args[1:])
 	case "set-env":
 		x.setEnv(rpcc, args[1:])
 	case "get-env":
@@ -236,6 +283,26 @@ func (x *CtlCommand) restartProcesses(rpcc *rpcclient.RPCClient, processes []str
 	x._startStopProcesses(rpcc, "restart", processes, "restarted", true)
 }
 
+// restart the processes of the program or group in batches
+func (x *CtlCommand) rollingRestart(rpcc *rpcclient.RPCClient, processes []string, maxUnavailable int, pause time.Duration) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify program or group for rolling restart")
+		return
+	}
+	for _, pname := range processes {
+		reply, err := rpcc.RollingRestart(&rpcclient.RollingRestartArg{
+			Name:           pname,
+			MaxUnavailable: maxUnavailable,
+			Pause:          pause,
+		})
+		if err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		x.showProcessInfo(reply.AllProcessInfo, make(map[string]bool))
+	}
+}
+
 // shutdown the supervisord
 func (x *CtlCommand) shutdown(rpcc *rpcclient.RPCClient) {
 	if reply, err := rpcc.Shutdown(); err == nil {
@@ -267,6 +334,129 @@ func (x *CtlCommand) reload(rpcc *rpcclient.RPCClient) {
 	}
 }
 
+// reload the configuration of the running programs without a restart
+func (x *CtlCommand) reloadPrograms(rpcc *rpcclient.RPCClient, processes []string) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify process for reload-program")
+		return
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.ReloadProcess(&rpcclient.ReloadProcessArg{Name: pname}); err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: reloaded\n", pname)
+	}
+}
+
+// stop and disable the programs, the disabled programs can't be started until they are enabled
+func (x *CtlCommand) disablePrograms(rpcc *rpcclient.RPCClient, processes []string) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify process for disable")
+		return
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.DisableProcess(&rpcclient.DisableProcessArg{Name: pname, Wait: true}); err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: disabled\n", pname)
+	}
+}
+
+// enable the disabled programs, they are not started by the enable
+func (x *CtlCommand) enablePrograms(rpcc *rpcclient.RPCClient, processes []string) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify process for enable")
+		return
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.EnableProcess(&rpcclient.EnableProcessArg{Name: pname}); err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: enabled\n", pname)
+	}
+}
+
+// freeze the running programs, the program can be <name> or <group>:*
+func (x *CtlCommand) pausePrograms(rpcc *rpcclient.RPCClient, processes []string) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify process for pause")
+		return
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.PauseProcess(&rpcclient.PauseProcessArg{Name: pname}); err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: paused\n", pname)
+	}
+}
+
+// thaw the paused programs
+func (x *CtlCommand) resumePrograms(rpcc *rpcclient.RPCClient, processes []string) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify process for resume")
+		return
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.ResumeProcess(&rpcclient.ResumeProcessArg{Name: pname}); err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: resumed\n", pname)
+	}
+}
+
+// scale the numprocs of the programs, the args are in <program>=<N> format
+func (x *CtlCommand) scale(rpcc *rpcclient.RPCClient, args []string) {
+	if len(args) <= 0 {
+		fmt.Println("Please specify <program>=<N> for scale")
+		return
+	}
+	for _, arg := range args {
+		pos := strings.Index(arg, "=")
+		if pos == -1 {
+			fmt.Printf("%s: invalid argument, need <program>=<N>\n", arg)
+			os.Exit(1)
+		}
+		name := arg[:pos]
+		numprocs, err := strconv.Atoi(arg[pos+1:])
+		if err != nil {
+			fmt.Printf("%s: invalid number [%v]\n", arg, err)
+			os.Exit(1)
+		}
+		reply, err := rpcc.ScaleProgram(&rpcclient.ScaleProgramArg{Name: name, Numprocs: numprocs})
+		if err != nil {
+			fmt.Printf("%s: failed [%v]\n", name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: scaled to %d\n", name, numprocs)
+		if len(reply.Added) > 0 {
+			fmt.Printf("Added: %s\n", strings.Join(reply.Added, ","))
+		}
+		if len(reply.Removed) > 0 {
+			fmt.Printf("Removed: %s\n", strings.Join(reply.Removed, ","))
+		}
+	}
+}
+
+// stop and remove the instances of the templates
+func (x *CtlCommand) removeInstances(rpcc *rpcclient.RPCClient, processes []string) {
+	if len(processes) <= 0 {
+		fmt.Println("Please specify the instance for remove, e.g. worker@tenant42")
+		return
+	}
+	for _, pname := range processes {
+		if _, err := rpcc.RemoveInstance(&rpcclient.RemoveInstanceArg{Name: pname, Wait: true}); err != nil {
+			fmt.Printf("%s: failed [%v]\n", pname, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: removed\n", pname)
+	}
+}
+
 func (x *CtlCommand) setEnv(rpcc *rpcclient.RPCClient, args []string) {
 	if len(args) < 2 {
 		fmt.Println("need two args for [key value]")
@@ -409,6 +599,10 @@ func (c *StopCommand) Execute(args []string) error {
 }
 
 func (rc *RestartCommand) Execute(args []string) error {
+	if rc.Rolling {
+		ctlCommand.rollingRestart(ctlCommand.createRpcClient(), args, rc.MaxUnavailable, rc.Pause)
+		return nil
+	}
 	ctlCommand.restartProcesses(ctlCommand.createRpcClient(), args)
 	return nil
 }
@@ -423,6 +617,41 @@ func (c *ReloadCommand) Execute(args []string) error {
 	return nil
 }
 
+func (c *ReloadProgramCommand) Execute(args []string) error {
+	ctlCommand.reloadPrograms(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
+func (c *DisableCommand) Execute(args []string) error {
+	ctlCommand.disablePrograms(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
+func (c *EnableCommand) Execute(args []string) error {
+	ctlCommand.enablePrograms(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
+func (c *PauseCommand) Execute(args []string) error {
+	ctlCommand.pausePrograms(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
+func (c *ResumeCommand) Execute(args []string) error {
+	ctlCommand.resumePrograms(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
+func (c *ScaleCommand) Execute(args []string) error {
+	ctlCommand.scale(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
+func (c *RemoveCommand) Execute(args []string) error {
+	ctlCommand.removeInstances(ctlCommand.createRpcClient(), args)
+	return nil
+}
+
 func (c *SetEnvCommand) Execute(args []string) error {
 	ctlCommand.setEnv(ctlCommand.createRpcClient(), args)
 	return nil
@@ -530,6 +759,34 @@ func init() {
 		"reload the programs",
 		"reload the programs",
 		&reloadCommand)
+	ctlCmd.AddCommand("reload-program",
+		"reload the programs in place",
+		"ask one or more running programs to reload their configuration by the reload_signal or reload_command",
+		&reloadProgramCommand)
+	ctlCmd.AddCommand("scale",
+		"scale the numprocs of programs",
+		"add or remove the processes of numprocs programs at runtime, e.g. scale worker=4",
+		&scaleCommand)
+	ctlCmd.AddCommand("remove",
+		"remove the instances of templates",
+		"stop and remove the instances created from the [program:name@] templates, e.g. remove worker@tenant42",
+		&removeCommand)
+	ctlCmd.AddCommand("disable",
+		"stop and disable the programs",
+		"stop one or more programs and keep them from being started by start, reload or autorestart until they are enabled",
+		&disableCommand)
+	ctlCmd.AddCommand("enable",
+		"enable the disabled programs",
+		"enable one or more disabled programs, they are started by start or reload later",
+		&enableCommand)
+	ctlCmd.AddCommand("pause",
+		"pause the programs",
+		"freeze one or more running programs, e.g. pause batch or pause jobs:*",
+		&pauseCommand)
+	ctlCmd.AddCommand("resume",
+		"resume the paused programs",
+		"thaw one or more paused programs",
+		&resumeCommand)
 	ctlCmd.AddCommand("get-env",
 		"get the global env",
 		"get the global env",
@@ -0,0 +1,162 @@
+package supd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaycc/supd/events"
+	"github.com/gwaycc/supd/process"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the RUNNING program in the format of the Prometheus file_sd, e.g.
+//
+//  [{"targets":["web-1:9000"],"labels":{"program":"web_1","group":"web","env":"prod"}}]
+type discoveryTarget struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// get the discovery file, it is disabled if discovery_file of [supervisord] is not configured.
+func (s *Supervisor) getDiscoveryFile() string {
+	entry, ok := s.config.GetSupervisord()
+	if !ok {
+		return ""
+	}
+	env := config.NewStringExpression("here", s.config.GetConfigFileDir())
+	file, err := env.Eval(entry.GetString("discovery_file", ""))
+	if err != nil {
+		return ""
+	}
+	return file
+}
+
+// get the host of the targets, it is the hostname by default.
+func (s *Supervisor) getDiscoveryHost() string {
+	if entry, ok := s.config.GetSupervisord(); ok {
+		if host := entry.GetString("discovery_host", ""); host != "" {
+			return host
+		}
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// get the RUNNING programs in the order of the names
+func (s *Supervisor) getDiscoveryTargets() []*discoveryTarget {
+	host := s.getDiscoveryHost()
+	procs := s.getAllProcesses(func(proc *process.Process) bool {
+		return proc.GetState() == process.RUNNING
+	})
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].GetName() < procs[j].GetName()
+	})
+	targets := []*discoveryTarget{}
+	for _, proc := range procs {
+		entry := proc.GetConfig()
+		target := &discoveryTarget{Targets: []string{}, Labels: map[string]string{}}
+		for _, addr := range proc.GetPorts() {
+			target.Targets = append(target.Targets, toDiscoveryAddr(host, addr))
+		}
+		if len(target.Targets) == 0 {
+			target.Targets = append(target.Targets, host)
+		}
+		// labels=env="prod",team="infra"
+		for _, kv := range entry.GetEnv("labels") {
+			pos := strings.Index(kv, "=")
+			target.Labels[kv[:pos]] = kv[pos+1:]
+		}
+		target.Labels["program"] = proc.GetName()
+		target.Labels["group"] = proc.GetGroup()
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// the port listened on all the interfaces is reached by the host
+func toDiscoveryAddr(host string, addr string) string {
+	h, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if h == "" || h == "0.0.0.0" || h == "::" {
+		h = host
+	}
+	return net.JoinHostPort(h, port)
+}
+
+// write the RUNNING programs to the discovery file
+func (s *Supervisor) saveDiscovery() error {
+	file := s.getDiscoveryFile()
+	if file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.getDiscoveryTargets(), "", "  ")
+	if err != nil {
+		return errors.As(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return errors.As(err, file)
+	}
+	// replace the file at once, so the readers never see a partial file
+	tmpFile := file + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return errors.As(err, tmpFile)
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		return errors.As(err, file)
+	}
+	return nil
+}
+
+// update the discovery file after the state of processes changed
+func (s *Supervisor) watchDiscovery() {
+	events.RegisterEventHandler("supd-discovery", []string{"PROCESS_STATE"}, func(event events.Event) {
+		// the handler is called with the lock of process, notify only.
+		s.notifyDiscoveryChanged()
+	})
+	s.watchers.Add(1)
+	go func() {
+		defer s.watchers.Done()
+		for {
+			select {
+			case <-s.discoveryChanged:
+			case <-s.done:
+				return
+			}
+			// merge the changes in a short time
+			time.Sleep(100 * time.Millisecond)
+			if err := s.saveDiscovery(); err != nil {
+				log.Warn("fail to save discovery file:", errors.As(err))
+			}
+		}
+	}()
+}
+
+// notify the RUNNING programs may be changed
+func (s *Supervisor) notifyDiscoveryChanged() {
+	select {
+	case s.discoveryChanged <- struct{}{}:
+	default:
+	}
+}
+
+// serve the RUNNING programs like the discovery file
+func (s *Supervisor) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(s.getDiscoveryTargets())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
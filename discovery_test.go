@@ -0,0 +1,118 @@
+package supd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestToDiscoveryAddr(t *testing.T) {
+	cases := map[string]string{
+		":8080":          "web-1:8080",
+		"0.0.0.0:8080":   "web-1:8080",
+		"[::]:8080":      "web-1:8080",
+		"127.0.0.1:9090": "127.0.0.1:9090",
+		"[::1]:7000":     "[::1]:7000",
+	}
+	for addr, expect := range cases {
+		if target := toDiscoveryAddr("web-1", addr); target != expect {
+			t.Errorf("%s: expect %s, but got %s", addr, expect, target)
+		}
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-discovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(`[supervisord]
+discovery_file=%(here)s/sd/targets.json
+discovery_host=web-1
+
+[program:web]
+command=/bin/sleep 30
+port_range=39200-39299
+ports=127.0.0.1:39300
+labels=env="prod",team=infra
+startsecs=0
+
+[program:worker]
+command=/bin/sleep 30
+startsecs=0
+
+[program:idle]
+command=/bin/sleep 30
+
+[group:g]
+programs=web,worker
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range s.config.GetPrograms() {
+		proc := s.createProcess(entry)
+		if proc.GetName() == "idle" {
+			continue
+		}
+		proc.Start(true)
+		defer proc.Stop(true)
+	}
+	port := s.procMgr.Find("web").GetConfig().GetString("port", "")
+
+	if err := s.saveDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sd", "targets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []*discoveryTarget{}
+	if err := json.Unmarshal(data, &targets); err != nil {
+		t.Fatal(err)
+	}
+	expect := []*discoveryTarget{
+		{
+			Targets: []string{"web-1:" + port, "127.0.0.1:39300"},
+			Labels:  map[string]string{"program": "web", "group": "g", "env": "prod", "team": "infra"},
+		},
+		{
+			Targets: []string{"web-1"},
+			Labels:  map[string]string{"program": "worker", "group": "g"},
+		},
+	}
+	if !reflect.DeepEqual(targets, expect) {
+		t.Fatalf("expect %s, but got %s", toJSON(expect), string(data))
+	}
+
+	// the endpoint serves the same targets
+	w := httptest.NewRecorder()
+	s.serveDiscovery(w, httptest.NewRequest("GET", "/discovery", nil))
+	targets = []*discoveryTarget{}
+	if err := json.Unmarshal(w.Body.Bytes(), &targets); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(targets, expect) {
+		t.Fatalf("expect %s, but got %s", toJSON(expect), w.Body.String())
+	}
+
+	// the program left RUNNING is removed
+	s.procMgr.Find("worker").Stop(true)
+	if targets := s.getDiscoveryTargets(); len(targets) != 1 || targets[0].Labels["program"] != "web" {
+		t.Fatalf("expect web only, but got %s", toJSON(targets))
+	}
+}
+
+func toJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
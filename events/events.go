@@ -44,8 +44,18 @@ type EventListenerManager struct {
 	namedListeners map[string]*EventListener
 	//mapping between the event name and the event listeners
 	eventListeners map[string]map[*EventListener]bool
+
+	handlerLock sync.RWMutex
+	//mapping between the event name and the named handlers inside supd
+	eventHandlers map[string]map[string]EventHandler
 }
 
+// EventHandler handle the events inside supd.
+//
+// The handler is called in the goroutine which emits the event, it may hold
+// the lock of the process, so it should not block or call back to the process.
+type EventHandler func(event Event)
+
 type EventPoolSerial struct {
 	sync.Mutex
 	poolserial map[string]uint64
@@ -236,6 +246,8 @@ var eventTypeDerives = map[string][]string{
 	"PROCESS_STATE_RUNNING":            {"EVENT", "PROCESS_STATE"},
 	"PROCESS_STATE_BACKOFF":            {"EVENT", "PROCESS_STATE"},
 	"PROCESS_STATE_STOPPING":           {"EVENT", "PROCESS_STATE"},
+	"PROCESS_STATE_PAUSED":             {"EVENT", "PROCESS_STATE"},
+	"PROCESS_STATE_STANDBY":            {"EVENT", "PROCESS_STATE"},
 	"PROCESS_STATE_EXITED":             {"EVENT", "PROCESS_STATE"},
 	"PROCESS_STATE_STOPPED":            {"EVENT", "PROCESS_STATE"},
 	"PROCESS_STATE_FATAL":              {"EVENT", "PROCESS_STATE"},
@@ -251,7 +263,10 @@ var eventTypeDerives = map[string][]string{
 	"TICK_60":                          {"EVENT", "TICK"},
 	"TICK_3600":                        {"EVENT", "TICK"},
 	"PROCESS_GROUP_ADDED":              {"EVENT", "PROCESS_GROUP"},
-	"PROCESS_GROUP_REMOVED":            {"EVENT", "PROCESS_GROUP"}}
+	"PROCESS_GROUP_REMOVED":            {"EVENT", "PROCESS_GROUP"},
+	"SUPERVISOR_CONFIG_RELOADED":       {"EVENT"},
+	"PROCESS_OUTPUT_TIMEOUT":           {"EVENT"},
+	"PROCESS_OUTPUT_MATCHED":           {"EVENT"}}
 var eventSerial uint64
 var eventListenerManager = NewEventListenerManager()
 var eventPoolSerial = NewEventPoolSerial()
@@ -291,14 +306,12 @@ func nextEventSerial() uint64 {
 
 func NewEventListenerManager() *EventListenerManager {
 	return &EventListenerManager{namedListeners: make(map[string]*EventListener),
-		eventListeners: make(map[string]map[*EventListener]bool)}
+		eventListeners: make(map[string]map[*EventListener]bool),
+		eventHandlers:  make(map[string]map[string]EventHandler)}
 }
 
-func (em *EventListenerManager) registerEventListener(eventListenerName string,
-	events []string,
-	listener *EventListener) {
-
-	em.namedListeners[eventListenerName] = listener
+// get all the final events of the events
+func deriveEvents(events []string) map[string]bool {
 	all_events := make(map[string]bool)
 	for _, event := range events {
 		for k, values := range eventTypeDerives {
@@ -313,6 +326,15 @@ func (em *EventListenerManager) registerEventListener(eventListenerName string,
 			}
 		}
 	}
+	return all_events
+}
+
+func (em *EventListenerManager) registerEventListener(eventListenerName string,
+	events []string,
+	listener *EventListener) {
+
+	em.namedListeners[eventListenerName] = listener
+	all_events := deriveEvents(events)
 	for event := range all_events {
 		log.WithFields(log.Fields{"eventListener": eventListenerName, "event": event}).Info("register event listener")
 		if _, ok := em.eventListeners[event]; !ok {
@@ -348,7 +370,41 @@ func UnregisterEventListener(eventListenerName string) *EventListener {
 	return eventListenerManager.unregisterEventListener(eventListenerName)
 }
 
+func (em *EventListenerManager) registerEventHandler(name string, events []string, handler EventHandler) {
+	em.handlerLock.Lock()
+	defer em.handlerLock.Unlock()
+	for event := range deriveEvents(events) {
+		if _, ok := em.eventHandlers[event]; !ok {
+			em.eventHandlers[event] = make(map[string]EventHandler)
+		}
+		em.eventHandlers[event][name] = handler
+	}
+}
+
+// RegisterEventHandler register or replace the named handler of the events inside supd.
+func RegisterEventHandler(name string, events []string, handler EventHandler) {
+	eventListenerManager.registerEventHandler(name, events, handler)
+}
+
+func (em *EventListenerManager) unregisterEventHandler(name string) {
+	em.handlerLock.Lock()
+	defer em.handlerLock.Unlock()
+	for _, handlers := range em.eventHandlers {
+		delete(handlers, name)
+	}
+}
+
+func UnregisterEventHandler(name string) {
+	eventListenerManager.unregisterEventHandler(name)
+}
+
 func (em *EventListenerManager) EmitEvent(event Event) {
+	em.handlerLock.RLock()
+	for _, handler := range em.eventHandlers[event.GetType()] {
+		handler(event)
+	}
+	em.handlerLock.RUnlock()
+
 	listeners, ok := em.eventListeners[event.GetType()]
 	if ok {
 		log.WithFields(log.Fields{"event": event.GetType()}).Info("process event")
@@ -570,6 +626,36 @@ func CreateProcessBackoffEvent(process string,
 	return r
 }
 
+func CreateProcessPausedEvent(process string,
+	group string,
+	from_state string,
+	pid int) *ProcessStateEvent {
+	r := &ProcessStateEvent{process_name: process,
+		group_name: group,
+		from_state: from_state,
+		tries:      -1,
+		expected:   -1,
+		pid:        pid}
+	r.eventType = "PROCESS_STATE_PAUSED"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// the program waits for the singleton lock held by the other host
+func CreateProcessStandbyEvent(process string,
+	group string,
+	from_state string) *ProcessStateEvent {
+	r := &ProcessStateEvent{process_name: process,
+		group_name: group,
+		from_state: from_state,
+		tries:      -1,
+		expected:   -1,
+		pid:        0}
+	r.eventType = "PROCESS_STATE_STANDBY"
+	r.serial = nextEventSerial()
+	return r
+}
+
 func CreateProcessStoppingEvent(process string,
 	group string,
 	from_state string,
@@ -748,3 +834,69 @@ func CreateProcessGroupRemovedEvent(group_name string) *ProcessGroupEvent {
 	r.serial = nextEventSerial()
 	return r
 }
+
+// the config is reloaded, the body lists the added, changed and removed programs
+type ConfigReloadedEvent struct {
+	BaseEvent
+	added   []string
+	changed []string
+	removed []string
+}
+
+func (ce *ConfigReloadedEvent) GetBody() string {
+	return fmt.Sprintf("added:%s changed:%s removed:%s",
+		strings.Join(ce.added, ","),
+		strings.Join(ce.changed, ","),
+		strings.Join(ce.removed, ","))
+}
+
+func CreateConfigReloadedEvent(added []string, changed []string, removed []string) *ConfigReloadedEvent {
+	r := &ConfigReloadedEvent{added: added, changed: changed, removed: removed}
+
+	r.eventType = "SUPERVISOR_CONFIG_RELOADED"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// the program writes nothing to stdout and stderr in the output_timeout
+type ProcessOutputTimeoutEvent struct {
+	BaseEvent
+	process_name string
+	group_name   string
+	pid          int
+	silence      int
+}
+
+func (pe *ProcessOutputTimeoutEvent) GetBody() string {
+	return fmt.Sprintf("processname:%s groupname:%s pid:%d silence:%d", pe.process_name, pe.group_name, pe.pid, pe.silence)
+}
+
+func CreateProcessOutputTimeoutEvent(process string, group string, pid int, silence int) *ProcessOutputTimeoutEvent {
+	r := &ProcessOutputTimeoutEvent{process_name: process, group_name: group, pid: pid, silence: silence}
+
+	r.eventType = "PROCESS_OUTPUT_TIMEOUT"
+	r.serial = nextEventSerial()
+	return r
+}
+
+// the output line of the program matches the on_output rule, the body is the header and the line
+type ProcessOutputMatchedEvent struct {
+	BaseEvent
+	process_name string
+	group_name   string
+	pid          int
+	rule         string
+	line         string
+}
+
+func (pe *ProcessOutputMatchedEvent) GetBody() string {
+	return fmt.Sprintf("processname:%s groupname:%s pid:%d rule:%s\n%s", pe.process_name, pe.group_name, pe.pid, pe.rule, pe.line)
+}
+
+func CreateProcessOutputMatchedEvent(process string, group string, pid int, rule string, line string) *ProcessOutputMatchedEvent {
+	r := &ProcessOutputMatchedEvent{process_name: process, group_name: group, pid: pid, rule: rule, line: line}
+
+	r.eventType = "PROCESS_OUTPUT_MATCHED"
+	r.serial = nextEventSerial()
+	return r
+}
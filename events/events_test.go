@@ -200,3 +200,18 @@ func TestProcessUnknownEvent(t *testing.T) {
 		t.Error("Fail to encode the process unknown event")
 	}
 }
+
+func TestEventHandler(t *testing.T) {
+	received := []string{}
+	RegisterEventHandler("test-handler", []string{"PROCESS_STATE"}, func(event Event) {
+		received = append(received, event.GetType())
+	})
+	EmitEvent(CreateProcessStartingEvent("proc-1", "group-1", "STOPPED", 0))
+	EmitEvent(NewTickEvent("TICK_5", time.Now().Unix()))
+	UnregisterEventHandler("test-handler")
+	EmitEvent(CreateProcessFatalEvent("proc-1", "group-1", "BACKOFF"))
+
+	if len(received) != 1 || received[0] != "PROCESS_STATE_STARTING" {
+		t.Errorf("Fail to handle the event: %v", received)
+	}
+}
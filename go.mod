@@ -11,7 +11,7 @@ require (
 	github.com/ochinchina/go-daemon v0.1.5
 	github.com/ochinchina/go-reaper v0.0.0-20181016012355-6b11389e79fc
 	github.com/sirupsen/logrus v1.4.2
-	gopkg.in/fsnotify.v1 v1.4.7 // indirect
+	gopkg.in/fsnotify.v1 v1.4.7
 	gopkg.in/go-ini/ini.v1 v1.46.0
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 )
@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gwaycc/supd/events"
 	"github.com/gwaycc/supd/faults"
@@ -176,6 +179,111 @@ func (l *LogCaptureLogger) ClearAllLogFile() error {
 	return l.underlineLogger.ClearAllLogFile()
 }
 
+// the logger records the time of the last write in unix nanoseconds, the silent program can be found by it.
+type ActivityLogger struct {
+	underlineLogger Logger
+	lastWrite       *int64
+}
+
+func NewActivityLogger(underlineLogger Logger, lastWrite *int64) *ActivityLogger {
+	return &ActivityLogger{underlineLogger: underlineLogger, lastWrite: lastWrite}
+}
+
+func (l *ActivityLogger) SetPid(pid int) {
+	l.underlineLogger.SetPid(pid)
+}
+
+func (l *ActivityLogger) Write(p []byte) (int, error) {
+	atomic.StoreInt64(l.lastWrite, time.Now().UnixNano())
+	return l.underlineLogger.Write(p)
+}
+
+func (l *ActivityLogger) Close() error {
+	return l.underlineLogger.Close()
+}
+
+func (l *ActivityLogger) ReadLog(offset int64, length int64) (string, error) {
+	return l.underlineLogger.ReadLog(offset, length)
+}
+
+func (l *ActivityLogger) ReadTailLog(offset int64, length int64) (string, int64, bool, error) {
+	return l.underlineLogger.ReadTailLog(offset, length)
+}
+
+func (l *ActivityLogger) ClearCurLogFile() error {
+	return l.underlineLogger.ClearCurLogFile()
+}
+
+func (l *ActivityLogger) ClearAllLogFile() error {
+	return l.underlineLogger.ClearAllLogFile()
+}
+
+// the logger passes the complete lines of the output to the handler, the partial line is kept
+// until its end is written, and the line longer than the max line size is split.
+type LineLogger struct {
+	underlineLogger Logger
+	handler         func(line string)
+	lock            sync.Mutex
+	buf             []byte
+}
+
+// the max size of a line passed to the handler
+const maxLineSize = 64 * 1024
+
+func NewLineLogger(underlineLogger Logger, handler func(line string)) *LineLogger {
+	return &LineLogger{underlineLogger: underlineLogger, handler: handler}
+}
+
+func (l *LineLogger) SetPid(pid int) {
+	l.underlineLogger.SetPid(pid)
+}
+
+func (l *LineLogger) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	l.buf = append(l.buf, p...)
+	for {
+		pos := bytes.IndexByte(l.buf, '\n')
+		if pos < 0 && len(l.buf) < maxLineSize {
+			break
+		}
+		if pos < 0 || pos > maxLineSize {
+			pos = maxLineSize
+		}
+		line := strings.TrimSuffix(string(l.buf[:pos]), "\r")
+		if pos < len(l.buf) && l.buf[pos] == '\n' {
+			pos++
+		}
+		l.buf = l.buf[pos:]
+		l.handler(line)
+	}
+	// release the memory of the long output
+	if len(l.buf) == 0 {
+		l.buf = nil
+	}
+	l.lock.Unlock()
+	return l.underlineLogger.Write(p)
+}
+
+func (l *LineLogger) Close() error {
+	return l.underlineLogger.Close()
+}
+
+func (l *LineLogger) ReadLog(offset int64, length int64) (string, error) {
+	return l.underlineLogger.ReadLog(offset, length)
+}
+
+func (l *LineLogger) ReadTailLog(offset int64, length int64) (string, int64, bool, error) {
+	return l.underlineLogger.ReadTailLog(offset, length)
+}
+
+func (l *LineLogger) ClearCurLogFile() error {
+	return l.underlineLogger.ClearCurLogFile()
+}
+
+func (l *LineLogger) ClearAllLogFile() error {
+	return l.underlineLogger.ClearAllLogFile()
+}
+
 type NullLogEventEmitter struct {
 }
 
@@ -7,6 +7,8 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"unicode"
 
@@ -29,11 +31,24 @@ func init() {
 	log.SetLevel(log.DebugLevel)
 }
 
-func initSignals(s *Supervisor) {
+// handle the signals for the current supervisor, it is called once and the supervisor
+// is replaced by ctl reload of the whole supd.
+func initSignals(current func() *Supervisor) {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		sig := <-sigs
+		s := current()
+		if s.isAdoptChildren() {
+			log.WithFields(log.Fields{"signal": sig}).Info("receive a signal to exit, the children keep running for adopting")
+			// the state is saved with the detached children by the restarting supervisor
+			if !s.isRestarting() {
+				if err := s.saveState(); err != nil {
+					log.Warn("fail to save state:", err)
+				}
+			}
+			os.Exit(-1)
+		}
 		log.WithFields(log.Fields{"signal": sig}).Info("receive a signal to stop all process & exit")
 		s.procMgr.StopAllProcesses()
 		os.Exit(-1)
@@ -119,10 +134,17 @@ func findSupervisordConf() (string, error) {
 func RunServer() {
 	// infinite loop for handling Restart ('reload' command)
 	LoadEnvFile()
+	var current atomic.Value
+	var signalOnce sync.Once
 	for true {
 		options.Configuration, _ = findSupervisordConf()
 		s := NewSupervisor(options.Configuration)
-		initSignals(s)
+		current.Store(s)
+		signalOnce.Do(func() {
+			initSignals(func() *Supervisor {
+				return current.Load().(*Supervisor)
+			})
+		})
 		if sErr, _, _, _ := s.reload(); sErr != nil {
 			log.Fatal(sErr)
 		}
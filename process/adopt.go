@@ -0,0 +1,267 @@
+package process
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/gwaycc/supd/logger"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ChildState is the running child which can be adopted by the next supd.
+type ChildState struct {
+	Name      string `json:"name"`
+	Pid       int    `json:"pid"`
+	StartTime int64  `json:"start_time"`
+	// identify the process to avoid adopting a reused pid
+	Fingerprint string `json:"fingerprint"`
+	StdoutFifo  string `json:"stdout_fifo,omitempty"`
+	StderrFifo  string `json:"stderr_fifo,omitempty"`
+	// the child is frozen by ctl pause
+	Paused bool `json:"paused,omitempty"`
+}
+
+// the named pipe which forwards the output of an adoptable child to the logger.
+//
+// The child writes the fifo by a fd opened for reading and writing, so it will not get SIGPIPE when
+// supd exits, the output is buffered by the pipe until a new supd reattaches it, and the child
+// blocks on writing if the pipe buffer (64KB on linux) is full before that.
+type logFifo struct {
+	path   string
+	reader *os.File
+	done   chan struct{}
+}
+
+// open the fifo for reading.
+func openLogFifo(path string) (*logFifo, error) {
+	// open with O_NONBLOCK, or it blocks until a writer opens the fifo.
+	reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, errors.As(err, path)
+	}
+	return &logFifo{path: path, reader: reader, done: make(chan struct{})}, nil
+}
+
+// copy the fifo to out until all the writers of the fifo are closed.
+func (f *logFifo) start(out io.Writer) {
+	go func() {
+		io.Copy(out, f.reader)
+		close(f.done)
+	}()
+}
+
+// close the fifo after the buffered output is copied, the output of the
+// descendants which still hold the fifo is discarded after a while.
+func (f *logFifo) close() {
+	select {
+	case <-f.done:
+	case <-time.After(time.Second):
+	}
+	f.reader.Close()
+	os.Remove(f.path)
+}
+
+// create the fifo files for the child and return the writer of them.
+func (p *Process) createLogFifo(std string, out io.Writer) (*os.File, error) {
+	path := filepath.Join(p.adoptDir, p.GetName()+"."+std)
+	os.Remove(path)
+	if err := os.MkdirAll(p.adoptDir, 0700); err != nil {
+		return nil, errors.As(err, p.adoptDir)
+	}
+	if err := mkfifo(path, 0600); err != nil {
+		return nil, errors.As(err, path)
+	}
+	fifo, err := openLogFifo(path)
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	// the writer is a reader of the fifo too, no fd but the stdout and stderr is passed to the child.
+	w, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		fifo.reader.Close()
+		os.Remove(path)
+		return nil, errors.As(err, path)
+	}
+	// start copying after the writer is opened, or it reads EOF at once.
+	fifo.start(out)
+	p.logFifos = append(p.logFifos, fifo)
+	return w, nil
+}
+
+// redirect the output of the program to the named pipes so it can be reattached after supd restarts.
+func (p *Process) setAdoptableLog() error {
+	p.closeLogFifos()
+	stdout, err := p.createLogFifo("stdout", p.StdoutLog)
+	if err != nil {
+		return errors.As(err)
+	}
+	p.cmd.Stdout = stdout
+	p.closeAfterStart = append(p.closeAfterStart, stdout)
+
+	if p.StderrLog == p.StdoutLog {
+		p.cmd.Stderr = stdout
+		return nil
+	}
+	stderr, err := p.createLogFifo("stderr", p.StderrLog)
+	if err != nil {
+		return errors.As(err)
+	}
+	p.cmd.Stderr = stderr
+	p.closeAfterStart = append(p.closeAfterStart, stderr)
+	return nil
+}
+
+func (p *Process) closeLogFifos() {
+	for _, f := range p.logFifos {
+		f.close()
+	}
+	p.logFifos = nil
+}
+
+// get the state of the child for adopting, nil if it can't be adopted.
+func (p *Process) GetChildState() *ChildState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	// the ProcessState is set by the Cmd.Wait without the lock, the exited child has no fingerprint
+	if !p.isAdoptable() || p.detached || p.cmd == nil || p.cmd.Process == nil {
+		return nil
+	}
+	if p.state != STARTING && p.state != RUNNING && p.state != STOPPING && p.state != PAUSED {
+		return nil
+	}
+	fingerprint, err := procFingerprint(p.cmd.Process.Pid)
+	if err != nil {
+		return nil
+	}
+	child := &ChildState{
+		Name:        p.GetName(),
+		Pid:         p.cmd.Process.Pid,
+		StartTime:   p.startTime.Unix(),
+		Fingerprint: fingerprint,
+		Paused:      p.state == PAUSED,
+	}
+	for _, f := range p.logFifos {
+		switch filepath.Ext(f.path) {
+		case ".stdout":
+			child.StdoutFifo = f.path
+		case ".stderr":
+			child.StderrFifo = f.path
+		}
+	}
+	return child
+}
+
+// Detach leave the running child to the next supervisor in the same supd, e.g. by ctl reload
+// of the whole supd, call without lock.
+//
+// The child is not stopped or restarted by the process any more, and the fifo files and the notify
+// socket are kept for the next supervisor which adopts the child by the saved ChildState.
+// Return false if the child can't be adopted.
+func (p *Process) Detach() bool {
+	if p.GetChildState() == nil {
+		return false
+	}
+	p.stopWatch()
+	p.lock.Lock()
+	p.detached = true
+	// the start loop does not restart the child after it exits
+	p.stopByUser = true
+	p.cancelStandby()
+	for _, f := range p.logFifos {
+		f.reader.Close()
+	}
+	p.logFifos = nil
+	n := p.notify
+	p.notify = nil
+	p.lock.Unlock()
+	if n != nil {
+		n.detach()
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("the program is left to the next supervisor")
+	return true
+}
+
+// true if the child keeps running after supd exits. The program with singleton_lock is not adoptable,
+// the lock is released when supd exits and the program would run on the other host too.
+func (p *Process) isAdoptable() bool {
+	return p.adoptDir != "" && p.getSingletonLock() == ""
+}
+
+// Adopt the running child which was started by the previous supd,
+// the process is supervised like it is started by Start.
+func (p *Process) Adopt(child *ChildState) error {
+	fingerprint, err := procFingerprint(child.Pid)
+	if err != nil || fingerprint != child.Fingerprint {
+		return errors.New("the child is gone").As(child.Name, child.Pid)
+	}
+	p.lock.Lock()
+	p.adopting = child
+	p.lock.Unlock()
+	p.Start(false)
+	return nil
+}
+
+// attach the process to the adopted child, call with lock.
+func (p *Process) adoptProgram(child *ChildState) error {
+	proc, err := os.FindProcess(child.Pid)
+	if err != nil {
+		return errors.As(err, child.Pid)
+	}
+	if err := p.createLoggers(); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to create the logger of adopted program", errors.As(err))
+		p.StdoutLog = logger.NewNullLogger(logger.NewNullLogEventEmitter())
+		p.StderrLog = p.StdoutLog
+	}
+	p.closeLogFifos()
+	for _, f := range []struct {
+		path string
+		out  io.Writer
+	}{{child.StdoutFifo, p.StdoutLog}, {child.StderrFifo, p.StderrLog}} {
+		if f.path == "" {
+			continue
+		}
+		fifo, err := openLogFifo(f.path)
+		if err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to reattach the log of adopted program", errors.As(err))
+			continue
+		}
+		fifo.start(f.out)
+		p.logFifos = append(p.logFifos, fifo)
+	}
+	p.StdoutLog.SetPid(child.Pid)
+	p.StderrLog.SetPid(child.Pid)
+
+	p.cmd = &exec.Cmd{Process: proc}
+	p.adopted = child
+	// the child paused by SIGSTOP is not moved, it can't be resumed by the cgroup
+	p.attachCgroup(child.Pid, !child.Paused)
+	// the child keeps the NOTIFY_SOCKET of the previous supd
+	if p.notify == nil && p.isNotifyEnabled() {
+		if err := p.openNotify(); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to open the notify socket of adopted program", errors.As(err))
+		}
+	}
+	p.startTime = time.Unix(child.StartTime, 0)
+	log.WithFields(log.Fields{"program": p.GetName(), "pid": child.Pid}).Info("success to adopt program")
+	p.changeStateTo(RUNNING)
+	if child.Paused {
+		p.changeStateTo(PAUSED)
+	}
+	return nil
+}
+
+// wait the adopted child exit, it is not the child of supd and can't be waited.
+func (p *Process) waitAdopted(child *ChildState) error {
+	for {
+		fingerprint, err := procFingerprint(child.Pid)
+		if err != nil || fingerprint != child.Fingerprint {
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
@@ -0,0 +1,48 @@
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+
+	"github.com/gwaylib/errors"
+)
+
+func set_adoptable(sysProcAttr *syscall.SysProcAttr) {
+	sysProcAttr.Setpgid = true
+}
+
+func mkfifo(path string, mode uint32) error {
+	return syscall.Mkfifo(path, mode)
+}
+
+// get the fingerprint of the running process by the boot id and the start time of it,
+// a pid reused by another process or after reboot has a different fingerprint.
+func procFingerprint(pid int) (string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", errors.As(err, pid)
+	}
+	// the command name in the stat may contain spaces, the fields start after it.
+	stat := string(data)
+	pos := strings.LastIndex(stat, ")")
+	if pos == -1 {
+		return "", errors.New("invalid stat").As(pid)
+	}
+	fields := strings.Fields(stat[pos+1:])
+	// fields[0] is the state, fields[19] is the start time
+	if len(fields) < 20 {
+		return "", errors.New("invalid stat").As(pid)
+	}
+	if fields[0] == "Z" || fields[0] == "X" {
+		return "", errors.New("process exited").As(pid)
+	}
+	bootId, err := ioutil.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return "", errors.As(err)
+	}
+	return strings.TrimSpace(string(bootId)) + ":" + fields[19], nil
+}
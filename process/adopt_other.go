@@ -0,0 +1,22 @@
+// +build !linux
+
+package process
+
+import (
+	"syscall"
+
+	"github.com/gwaylib/errors"
+)
+
+// adopting is only supported on linux, the child is still killed with supd.
+func set_adoptable(sysProcAttr *syscall.SysProcAttr) {
+	set_deathsig(sysProcAttr)
+}
+
+func mkfifo(path string, mode uint32) error {
+	return errors.New("fifo is not supported").As(path)
+}
+
+func procFingerprint(pid int) (string, error) {
+	return "", errors.New("adopting is not supported").As(pid)
+}
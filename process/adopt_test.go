@@ -0,0 +1,119 @@
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// create the adoptable program which writes a line in every 100ms, the fifo files are in dir/children
+func newAdoptTestProcess(t *testing.T, dir string) *Process {
+	script := filepath.Join(dir, "child.sh")
+	if err := ioutil.WriteFile(script, []byte("while true; do echo line; sleep 0.1; done\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	proc := newTestProcess(t, dir, `[program:adopt]
+command=/bin/sh %(here)s/child.sh
+startsecs=0
+stdout_logfile=%(here)s/adopt.log
+`)
+	proc.adoptDir = filepath.Join(dir, "children")
+	return proc
+}
+
+func TestAdoptableLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newAdoptTestProcess(t, dir)
+	proc.Start(true)
+	defer proc.Stop(true)
+	if proc.GetState() != RUNNING {
+		t.Fatalf("expect RUNNING, but it is %s", proc.GetState())
+	}
+
+	// the shell holds the script, only the fifo files are checked
+	fdDir := fmt.Sprintf("/proc/%d/fd", proc.GetPid())
+	fds, err := ioutil.ReadDir(fdDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fd := range fds {
+		target, _ := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if fd.Name() != "1" && fd.Name() != "2" && strings.HasPrefix(target, proc.adoptDir) {
+			t.Fatalf("expect only the stdout and stderr are the fifo, but fd %s is %s", fd.Name(), target)
+		}
+	}
+
+	// the child is not killed by SIGPIPE when the reader of supd is gone
+	proc.lock.Lock()
+	proc.closeLogFifos()
+	proc.lock.Unlock()
+	time.Sleep(1 * time.Second)
+	if proc.GetState() != RUNNING {
+		t.Fatalf("expect the child keeps running, but it is %s", proc.GetState())
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	first := newAdoptTestProcess(t, dir)
+	first.Start(true)
+	defer first.Stop(true)
+	child := first.GetChildState()
+	if child == nil || child.Pid != first.GetPid() || child.StdoutFifo == "" {
+		t.Fatalf("expect the child can be adopted, but got %+v", child)
+	}
+
+	// the next supd can't adopt a reused pid
+	second := newAdoptTestProcess(t, dir)
+	gone := *child
+	gone.Fingerprint = "boot:0"
+	if err := second.Adopt(&gone); err == nil {
+		t.Fatal("expect the child with another fingerprint is not adopted")
+	}
+
+	// the next supd reattaches the output after the previous supd exits, the fifo files are kept
+	first.lock.Lock()
+	for _, f := range first.logFifos {
+		f.reader.Close()
+	}
+	first.lock.Unlock()
+	if err := second.Adopt(child); err != nil {
+		t.Fatal(err)
+	}
+	defer second.Stop(true)
+	if !waitState(second, RUNNING, 5) || second.GetPid() != child.Pid {
+		t.Fatalf("expect the child %d is adopted, but it is %s with pid %d", child.Pid, second.GetState(), second.GetPid())
+	}
+	logFile := filepath.Join(dir, "adopt.log")
+	os.Truncate(logFile, 0)
+	time.Sleep(1 * time.Second)
+	if data, _ := ioutil.ReadFile(logFile); !strings.Contains(string(data), "line") {
+		t.Fatalf("expect the output of adopted child is in the log, but got %q", data)
+	}
+	if state := second.GetChildState(); state == nil || state.Pid != child.Pid {
+		t.Fatalf("expect the adopted child can be adopted again, but got %+v", state)
+	}
+
+	// the adopted child is stopped by the signals, and its exit is found without waiting it
+	second.Stop(true)
+	if second.GetState() == RUNNING {
+		t.Fatalf("expect the adopted child is stopped, but it is %s", second.GetState())
+	}
+	if _, err := procFingerprint(child.Pid); err == nil {
+		t.Fatal("expect the adopted child exits")
+	}
+}
@@ -0,0 +1,205 @@
+// +build linux
+
+package process
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gwaylib/errors"
+)
+
+// the freezer cgroup created by supd for the program, it is a child of the cgroup of supd.
+//
+// The cgroup freezer of v1 is used if it is mounted, or the cgroup.freeze of v2.
+type freezerCgroup struct {
+	// the path in the hierarchy, e.g. /system.slice/supd.service/supd-name
+	path string
+	// the directory of the cgroup in the mounted hierarchy
+	dir string
+	v2  bool
+}
+
+// find the hierarchy of the freezer, return the mount point, the mounted root and true if it is v2.
+func findFreezerMount() (string, string, bool, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", "", false, errors.As(err)
+	}
+	defer file.Close()
+	v2Mount, v2Root := "", ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 38 32 0:34 / /sys/fs/cgroup/freezer rw,relatime - cgroup cgroup rw,freezer
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || len(fields) < sep+4 {
+			continue
+		}
+		switch fields[sep+1] {
+		case "cgroup":
+			for _, opt := range strings.Split(fields[sep+3], ",") {
+				if opt == "freezer" {
+					return fields[4], fields[3], false, nil
+				}
+			}
+		case "cgroup2":
+			v2Mount, v2Root = fields[4], fields[3]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", false, errors.As(err)
+	}
+	if v2Mount == "" {
+		return "", "", false, errors.New("the cgroup freezer is not mounted")
+	}
+	return v2Mount, v2Root, true, nil
+}
+
+// get the path of the process in the freezer hierarchy
+func procCgroupPath(pid int, v2 bool) (string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", errors.As(err, pid)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if v2 && fields[0] == "0" && fields[1] == "" {
+			return fields[2], nil
+		}
+		if !v2 {
+			for _, controller := range strings.Split(fields[1], ",") {
+				if controller == "freezer" {
+					return fields[2], nil
+				}
+			}
+		}
+	}
+	return "", errors.New("the process is not in the freezer hierarchy").As(pid)
+}
+
+// get the cgroup of the program under the cgroup of supd, it is not created.
+func getFreezerCgroup(name string) (*freezerCgroup, error) {
+	mount, root, v2, err := findFreezerMount()
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	self, err := procCgroupPath(os.Getpid(), v2)
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	cgPath := filepath.Join(self, "supd-"+strings.Replace(name, "/", "_", -1))
+	rel := strings.TrimPrefix(cgPath, root)
+	if root != "/" && rel == cgPath {
+		return nil, errors.New("the cgroup of supd is not mounted").As(self, root)
+	}
+	return &freezerCgroup{path: cgPath, dir: filepath.Join(mount, rel), v2: v2}, nil
+}
+
+// create the cgroup and move the process tree into it, the children forked after moving are in it too.
+func (c *freezerCgroup) attach(pid int) error {
+	if err := os.Mkdir(c.dir, 0755); err != nil && !os.IsExist(err) {
+		return errors.As(err, c.dir)
+	}
+	return c.move(pid)
+}
+
+// move the process and its descendants into the cgroup, the children are listed after the parent is moved,
+// so the children forked by the program before it is moved are not missed.
+func (c *freezerCgroup) move(pid int) error {
+	if err := ioutil.WriteFile(filepath.Join(c.dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return errors.As(err, c.dir, pid)
+	}
+	tasks, _ := filepath.Glob(fmt.Sprintf("/proc/%d/task/*/children", pid))
+	for _, task := range tasks {
+		data, err := ioutil.ReadFile(task)
+		if err != nil {
+			continue
+		}
+		for _, field := range strings.Fields(string(data)) {
+			child, err := strconv.Atoi(field)
+			if err != nil {
+				continue
+			}
+			if err := c.move(child); err != nil {
+				// the child may exit at any time
+				if _, statErr := os.Stat(fmt.Sprintf("/proc/%d", child)); statErr == nil {
+					return errors.As(err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// true if the process is in the cgroup
+func (c *freezerCgroup) contains(pid int) bool {
+	path, err := procCgroupPath(pid, c.v2)
+	return err == nil && path == c.path
+}
+
+// freeze or thaw all the processes in the cgroup, wait at most 1 second for the freezing.
+func (c *freezerCgroup) freeze(freeze bool) error {
+	file, value := filepath.Join(c.dir, "freezer.state"), "THAWED"
+	if freeze {
+		value = "FROZEN"
+	}
+	if c.v2 {
+		file, value = filepath.Join(c.dir, "cgroup.freeze"), "0"
+		if freeze {
+			value = "1"
+		}
+	}
+	if err := ioutil.WriteFile(file, []byte(value), 0644); err != nil {
+		return errors.As(err, file)
+	}
+	for i := 0; i < 100; i++ {
+		if c.isFrozen() == freeze {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return errors.New("the cgroup is not frozen in time").As(c.dir, value)
+}
+
+// true if the cgroup is frozen
+func (c *freezerCgroup) isFrozen() bool {
+	file, expect := filepath.Join(c.dir, "freezer.state"), "FROZEN"
+	if c.v2 {
+		file, expect = filepath.Join(c.dir, "cgroup.events"), "frozen 1"
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == expect {
+			return true
+		}
+	}
+	return false
+}
+
+// remove the cgroup, it is kept if any process is still in it.
+func (c *freezerCgroup) remove() error {
+	if err := os.Remove(c.dir); err != nil && !os.IsNotExist(err) {
+		return errors.As(err, c.dir)
+	}
+	return nil
+}
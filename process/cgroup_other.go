@@ -0,0 +1,34 @@
+// +build !linux
+
+package process
+
+import (
+	"github.com/gwaylib/errors"
+)
+
+// the cgroup freezer is only supported on linux
+type freezerCgroup struct{}
+
+func getFreezerCgroup(name string) (*freezerCgroup, error) {
+	return nil, errors.New("cgroup is not supported").As(name)
+}
+
+func (c *freezerCgroup) attach(pid int) error {
+	return errors.New("cgroup is not supported").As(pid)
+}
+
+func (c *freezerCgroup) contains(pid int) bool {
+	return false
+}
+
+func (c *freezerCgroup) freeze(freeze bool) error {
+	return errors.New("cgroup is not supported")
+}
+
+func (c *freezerCgroup) isFrozen() bool {
+	return false
+}
+
+func (c *freezerCgroup) remove() error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// split the value of the condition key by the comma and the spaces
+func splitCondition(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// check the conditions of the program before starting it, the program is skipped if any condition fails.
+//
+//  condition_path_exists=/data/vol,!/etc/x.disabled  - all the paths exist, or not exist with the ! prefix
+//  condition_env=ROLE=web,DATA_DIR,!MAINTENANCE       - all the environments of supd are set or set to the value
+//  condition_host=web-*,api-?                         - the host name matches one of the patterns
+//  condition_command=/usr/bin/test -d /data           - the command exits with 0
+func (p *Process) CheckConditions() error {
+	if !p.config.IsProgram() {
+		return nil
+	}
+	for _, path := range splitCondition(p.config.GetStringExpression("condition_path_exists", "")) {
+		negate := strings.HasPrefix(path, "!")
+		path = strings.TrimPrefix(path, "!")
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.config.ConfigDir, path)
+		}
+		_, err := os.Stat(path)
+		if exists := err == nil; exists == negate {
+			if negate {
+				return fmt.Errorf("condition_path_exists: %s exists", path)
+			}
+			return fmt.Errorf("condition_path_exists: %s does not exist", path)
+		}
+	}
+
+	for _, env := range splitCondition(p.config.GetString("condition_env", "")) {
+		negate := strings.HasPrefix(env, "!")
+		env = strings.TrimPrefix(env, "!")
+		kv := strings.SplitN(env, "=", 2)
+		value := os.Getenv(kv[0])
+		matched := value != ""
+		if len(kv) == 2 {
+			matched = value == kv[1]
+		}
+		if matched == negate {
+			return fmt.Errorf("condition_env: %s is not met", env)
+		}
+	}
+
+	if hosts := splitCondition(p.config.GetString("condition_host", "")); len(hosts) > 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("condition_host: %v", err)
+		}
+		matched := false
+		for _, pattern := range hosts {
+			if ok, _ := filepath.Match(pattern, hostname); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("condition_host: %s does not match %s", hostname, strings.Join(hosts, ","))
+		}
+	}
+
+	if p.config.GetString("condition_command", "") != "" {
+		timeout := time.Duration(p.config.GetInt("condition_command_timeout", 10)) * time.Second
+		if err := p.runCommand("condition_command", timeout); err != nil {
+			return fmt.Errorf("condition_command: %s fails", p.config.GetString("condition_command", ""))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-condition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "data"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("SUPD_CONDITION_ROLE", "web")
+	defer os.Unsetenv("SUPD_CONDITION_ROLE")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		conditions string
+		met        bool
+	}{
+		{"", true},
+		{"condition_path_exists=" + dir + "/data, !" + dir + "/missing", true},
+		{"condition_path_exists=data", true},
+		{"condition_path_exists=" + dir + "/missing", false},
+		{"condition_path_exists=!" + dir + "/data", false},
+		{"condition_env=SUPD_CONDITION_ROLE,SUPD_CONDITION_ROLE=web,!SUPD_CONDITION_UNSET", true},
+		{"condition_env=SUPD_CONDITION_ROLE=db", false},
+		{"condition_env=SUPD_CONDITION_UNSET", false},
+		{"condition_host=no-such-host-*," + hostname, true},
+		{"condition_host=no-such-host-*", false},
+		{"condition_command=/bin/test -f " + dir + "/data", true},
+		{"condition_command=/bin/false", false},
+	}
+	for i, c := range cases {
+		proc := newTestProcess(t, dir, "[program:condition]\ncommand=/bin/sleep 30\n"+c.conditions+"\n")
+		err := proc.CheckConditions()
+		if (err == nil) != c.met {
+			t.Fatalf("case %d: %s, expect met %t, but got %v", i, c.conditions, c.met, err)
+		}
+	}
+}
+
+func TestStartSkipped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-condition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:condition]
+command=/bin/sleep 30
+startsecs=0
+condition_path_exists=`+dir+`/data
+`)
+	proc.Start(true)
+	if proc.GetState() != STOPPED || proc.GetSkipReason() == "" {
+		t.Fatalf("expect the program is skipped, but it is %s", proc.GetState())
+	}
+	if !strings.HasPrefix(proc.GetDescription(), "SKIPPED: condition_path_exists") {
+		t.Fatalf("unexpected description: %s", proc.GetDescription())
+	}
+
+	// the conditions are checked again by the next start
+	if err := ioutil.WriteFile(filepath.Join(dir, "data"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	proc.Start(true)
+	defer proc.Stop(true)
+	if proc.GetState() != RUNNING || proc.GetSkipReason() != "" {
+		t.Fatalf("expect the program is running, but it is %s", proc.GetState())
+	}
+}
@@ -0,0 +1,33 @@
+// +build !windows
+
+package process
+
+import (
+	"syscall"
+
+	"github.com/gwaycc/supd/signals"
+	"github.com/gwaylib/errors"
+)
+
+// freeze or thaw the process tree of the program by the freezer cgroup created by supd, or by the SIGSTOP and
+// SIGCONT to the process group if the program is not in the cgroup. Call with lock.
+//
+// The cgroup of the program which is not created by supd is never frozen, it may be shared with supd or the
+// other services.
+func (p *Process) freeze(freeze bool) error {
+	pid := p.cmd.Process.Pid
+	if p.cgroup != nil && p.cgroup.contains(pid) {
+		return p.cgroup.freeze(freeze)
+	}
+	sig := syscall.SIGCONT
+	if freeze {
+		sig = syscall.SIGSTOP
+	}
+	// the daemonized or adopted program may be not the leader of its process group
+	if err := signals.Kill(p.cmd.Process, sig, true); err != nil {
+		if err := signals.Kill(p.cmd.Process, sig, false); err != nil {
+			return errors.As(err, pid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package process
+
+import (
+	"github.com/gwaylib/errors"
+)
+
+func (p *Process) freeze(freeze bool) error {
+	return errors.New("pause is not supported on windows").As(p.GetName())
+}
@@ -0,0 +1,115 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/gwaycc/supd/signals"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the hooks of the program lifecycle
+const (
+	HOOK_PRE_START  = "pre_start"
+	HOOK_POST_START = "post_start"
+	HOOK_PRE_STOP   = "pre_stop"
+	HOOK_POST_STOP  = "post_stop"
+)
+
+// get the timeout of the hook, it is configured by <hook>_timeout in seconds.
+func (p *Process) getHookTimeout(hook string) time.Duration {
+	return time.Duration(p.config.GetInt(hook+"_timeout", 30)) * time.Second
+}
+
+// true if the hook is configured
+func (p *Process) hasHook(hook string) bool {
+	return p.config.IsProgram() && p.config.GetString(hook, "") != ""
+}
+
+// run the hook command of the program and wait it exits, call without lock.
+func (p *Process) runHook(hook string) error {
+	if !p.hasHook(hook) {
+		return nil
+	}
+	return p.runCommand(hook, p.getHookTimeout(hook), "SUPD_HOOK="+hook)
+}
+
+// run the command configured by the key and wait it exits, call without lock.
+//
+// The command runs with the environment, user and directory of the program,
+// and its output is written to the logs of the program.
+// It is killed if it does not exit in the timeout.
+func (p *Process) runCommand(key string, timeout time.Duration, env ...string) error {
+	return p.runCommandLine(key, p.config.GetStringExpression(key, ""), timeout, env...)
+}
+
+// run the command line like runCommand, the key names the command in the logs and errors.
+func (p *Process) runCommandLine(key string, command string, timeout time.Duration, env ...string) error {
+	args, err := parseCommand(command)
+	if err != nil {
+		return errors.As(err, key)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	set_deathsig(cmd.SysProcAttr)
+	if err := p.setCmdUser(cmd); err != nil {
+		return errors.As(err, key)
+	}
+	progEnv, err := p.getEnv()
+	if err != nil {
+		return errors.As(err, key)
+	}
+	cmd.Env = append(progEnv, env...)
+	cmd.Dir = p.getDir()
+	cmd.Stdout, cmd.Stderr = p.getHookOutput()
+
+	log.WithFields(log.Fields{"program": p.GetName(), "command": key}).Info("run the command of program")
+	if err := cmd.Start(); err != nil {
+		return errors.As(err, key)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return errors.As(err, key)
+		}
+		return nil
+	case <-time.After(timeout):
+		// kill the command and its children
+		signals.Kill(cmd.Process, syscall.SIGKILL, true)
+		<-done
+		return errors.New(fmt.Sprintf("the command is timeout after %v", timeout)).As(key)
+	}
+}
+
+// run the hook in background, the error is logged only.
+func (p *Process) runHookAsync(hook string) {
+	if !p.hasHook(hook) {
+		return
+	}
+	go func() {
+		if err := p.runHook(hook); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName(), "hook": hook}).Warn("fail to run the hook of program:", err)
+		}
+	}()
+}
+
+// get the writers of the hook output, the output is discarded if the logs are not created.
+func (p *Process) getHookOutput() (stdout io.Writer, stderr io.Writer) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.StdoutLog != nil {
+		stdout = p.StdoutLog
+	}
+	if p.StderrLog != nil {
+		stderr = p.StderrLog
+	}
+	return stdout, stderr
+}
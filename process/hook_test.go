@@ -0,0 +1,79 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:hook]
+command=/bin/cat
+directory=`+dir+`
+environment=HOOK_VALUE=hello
+stdout_logfile=`+dir+`/hook.log
+pre_start=/usr/bin/env
+post_start=/bin/false
+pre_stop=/bin/sleep 5
+pre_stop_timeout=1
+`)
+	if err := proc.createLoggers(); err != nil {
+		t.Fatal(err)
+	}
+	defer proc.StdoutLog.Close()
+
+	if err := proc.runHook(HOOK_PRE_START); err != nil {
+		t.Fatal(err)
+	}
+	output, err := ioutil.ReadFile(filepath.Join(dir, "hook.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), "HOOK_VALUE=hello") || !strings.Contains(string(output), "SUPD_HOOK=pre_start") {
+		t.Errorf("the output of hook is not in the log: %s", output)
+	}
+	if err := proc.runHook(HOOK_POST_START); err == nil {
+		t.Error("expect the hook fails")
+	}
+	if err := proc.runHook(HOOK_PRE_STOP); err == nil {
+		t.Error("expect the hook is timeout")
+	}
+	if err := proc.runHook(HOOK_POST_STOP); err != nil {
+		t.Error("expect the hook is not configured")
+	}
+}
+
+func TestPreStartBackoff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:hook]
+command=/bin/sleep 30
+startsecs=0
+startretries=3
+pre_start=/bin/false
+`)
+	proc.Start(false)
+	defer proc.Stop(true)
+
+	// the failed pre_start is retried after a while
+	time.Sleep(500 * time.Millisecond)
+	if proc.GetState() != BACKOFF {
+		t.Fatalf("expect BACKOFF, but it is %s", proc.GetState())
+	}
+	if !waitState(proc, FATAL, 5) {
+		t.Fatalf("expect FATAL after the retries, but it is %s", proc.GetState())
+	}
+}
@@ -0,0 +1,256 @@
+// +build !windows
+
+package process
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the notify socket of the program, the program sends the sd_notify messages like under systemd:
+//
+//  READY=1 STATUS=... WATCHDOG=1 WATCHDOG=trigger MAINPID=<pid> STOPPING=1
+type notifySocket struct {
+	path string
+	conn *net.UnixConn
+}
+
+func (n *notifySocket) close() {
+	n.conn.Close()
+	os.Remove(n.path)
+}
+
+// close the socket and keep the file, the child keeps the path in its NOTIFY_SOCKET
+// and the next supervisor binds it again.
+func (n *notifySocket) detach() {
+	n.conn.Close()
+}
+
+// true if the program speaks the sd_notify protocol
+func (p *Process) isNotifyEnabled() bool {
+	return p.config.IsProgram() && p.notifyDir != "" && (p.isNotifyReady() || p.getWatchdogTimeout() > 0)
+}
+
+// true if the program is RUNNING after it sends READY=1 instead of after the startsecs
+func (p *Process) isNotifyReady() bool {
+	return p.config.GetBool("notify", false)
+}
+
+// get the timeout of the READY=1 message, the start fails if the program is not ready in time.
+func (p *Process) getNotifyTimeout() time.Duration {
+	return time.Duration(p.config.GetInt("notify_timeout", 90)) * time.Second
+}
+
+// get the timeout of the WATCHDOG=1 message, the program is restarted if it does not ping in time.
+func (p *Process) getWatchdogTimeout() time.Duration {
+	return time.Duration(p.config.GetInt("watchdog_sec", 0)) * time.Second
+}
+
+// open the notify socket and pass it to the program by NOTIFY_SOCKET, call with lock.
+func (p *Process) setNotify() error {
+	p.notifyReady = false
+	p.notifyStatus = ""
+	p.notifyStopping = false
+	p.notifyMainPid = 0
+	p.watchdogTrigger = false
+	if !p.isNotifyEnabled() {
+		return nil
+	}
+	if p.notify == nil {
+		if err := p.openNotify(); err != nil {
+			return errors.As(err)
+		}
+	}
+	p.cmd.Env = append(p.cmd.Env, "NOTIFY_SOCKET="+p.notify.path)
+	if timeout := p.getWatchdogTimeout(); timeout > 0 {
+		p.cmd.Env = append(p.cmd.Env, "WATCHDOG_USEC="+strconv.FormatInt(int64(timeout/time.Microsecond), 10))
+	}
+	return nil
+}
+
+// bind the notify socket of the program, call with lock.
+func (p *Process) openNotify() error {
+	if err := os.MkdirAll(p.notifyDir, 0755); err != nil {
+		return errors.As(err, p.notifyDir)
+	}
+	path := filepath.Join(p.notifyDir, p.GetName()+".sock")
+	os.Remove(path)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return errors.As(err, path)
+	}
+	if err := setNotifyPassCred(conn); err != nil {
+		conn.Close()
+		os.Remove(path)
+		return errors.As(err, path)
+	}
+	// only the user of the program can send to the socket
+	if err := os.Chmod(path, 0600); err != nil {
+		conn.Close()
+		os.Remove(path)
+		return errors.As(err, path)
+	}
+	uid, gid, ok, err := p.lookupUser()
+	if err == nil && ok {
+		err = os.Chown(path, int(uid), int(gid))
+	}
+	if err != nil {
+		conn.Close()
+		os.Remove(path)
+		return errors.As(err, path)
+	}
+	p.notify = &notifySocket{path: path, conn: conn}
+	go p.readNotify(p.notify)
+	return nil
+}
+
+// close the notify socket of the program
+func (p *Process) closeNotify() {
+	p.lock.Lock()
+	n := p.notify
+	p.notify = nil
+	p.lock.Unlock()
+	if n != nil {
+		n.close()
+	}
+}
+
+func (p *Process) readNotify(n *notifySocket) {
+	buf := make([]byte, 4096)
+	for {
+		size, pid, err := readNotifyMsg(n.conn, buf)
+		if err != nil {
+			return
+		}
+		p.handleNotify(pid, string(buf[:size]))
+	}
+}
+
+// true if the message is sent by the main process of the program, the MAINPID or their process group
+// like NotifyAccess=main of systemd, the pid is -1 if the sender is unknown. call with lock.
+func (p *Process) isNotifySender(pid int) bool {
+	if pid < 0 {
+		return true
+	}
+	if pid == 0 || p.cmd == nil || p.cmd.Process == nil {
+		return false
+	}
+	if pid == p.cmd.Process.Pid || pid == p.notifyMainPid {
+		return true
+	}
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return false
+	}
+	// the program is the leader of its process group
+	if pgid == p.cmd.Process.Pid {
+		return true
+	}
+	if p.notifyMainPid > 0 {
+		mainPgid, err := syscall.Getpgid(p.notifyMainPid)
+		return err == nil && pgid == mainPgid
+	}
+	return false
+}
+
+// handle the message of the notify socket, the message is the newline-separated KEY=VALUE
+func (p *Process) handleNotify(pid int, msg string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.isNotifySender(pid) {
+		log.WithFields(log.Fields{"program": p.GetName(), "pid": pid}).Warn("ignore the notify message of the other process")
+		return
+	}
+	for _, line := range strings.Split(msg, "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "READY":
+			if kv[1] == "1" && !p.notifyReady {
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("the program is ready")
+				p.notifyReady = true
+			}
+		case "STATUS":
+			p.notifyStatus = kv[1]
+		case "WATCHDOG":
+			if kv[1] == "1" {
+				p.lastWatchdog = time.Now()
+			} else if kv[1] == "trigger" {
+				p.watchdogTrigger = true
+			}
+		case "MAINPID":
+			if pid, err := strconv.Atoi(kv[1]); err == nil {
+				p.notifyMainPid = pid
+			}
+		case "STOPPING":
+			if kv[1] == "1" {
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("the program is stopping")
+				p.notifyStopping = true
+			}
+		}
+	}
+}
+
+// wait the program sends READY=1 before endTime, the program is killed if it is not ready in time.
+func (p *Process) monitorProgramIsReady(endTime time.Time, monitorExited *int32, programExited *int32) {
+	ready := false
+	for time.Now().Before(endTime) && atomic.LoadInt32(programExited) == 0 {
+		p.lock.RLock()
+		ready = p.notifyReady
+		p.lock.RUnlock()
+		if ready {
+			break
+		}
+		time.Sleep(time.Duration(100) * time.Millisecond)
+	}
+	atomic.StoreInt32(monitorExited, 1)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if atomic.LoadInt32(programExited) != 0 || p.state != STARTING {
+		return
+	}
+	if !ready {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("the program is not ready in time, kill it")
+		p.sendSignal(os.Kill, p.config.GetBool("killasgroup", p.config.GetBool("stopasgroup", false)))
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("success to start program")
+	p.changeStateTo(RUNNING)
+	p.runHookAsync(HOOK_POST_START)
+}
+
+// restart the program if it does not send WATCHDOG=1 in watchdog_sec, or it sends WATCHDOG=trigger.
+func (p *Process) monitorWatchdog(proc *os.Process) {
+	timeout := p.getWatchdogTimeout()
+	if timeout <= 0 {
+		return
+	}
+	for {
+		time.Sleep(time.Second)
+		p.lock.RLock()
+		current := !p.detached && p.cmd != nil && p.cmd.Process == proc && (p.state == STARTING || p.state == RUNNING || p.state == PAUSED)
+		expired := p.state == RUNNING && !p.notifyStopping && time.Now().Sub(p.lastWatchdog) > timeout
+		trigger := p.watchdogTrigger
+		p.lock.RUnlock()
+		if !current {
+			return
+		}
+		if expired || trigger {
+			log.WithFields(log.Fields{"program": p.GetName(), "pid": proc.Pid}).Warn("the watchdog of program is timeout, restart it")
+			p.Restart(false)
+			return
+		}
+	}
+}
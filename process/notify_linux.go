@@ -0,0 +1,48 @@
+// +build linux
+
+package process
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/gwaylib/errors"
+)
+
+// pass the credentials of the sender with the messages of the notify socket
+func setNotifyPassCred(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return errors.As(err)
+	}
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
+	}); err != nil {
+		return errors.As(err)
+	}
+	if sockErr != nil {
+		return errors.As(sockErr)
+	}
+	return nil
+}
+
+// read a message of the notify socket and the pid of the sender by the SCM_CREDENTIALS,
+// the pid is 0 if the credentials are missing.
+func readNotifyMsg(conn *net.UnixConn, buf []byte) (int, int, error) {
+	oob := make([]byte, syscall.CmsgSpace(syscall.SizeofUcred))
+	size, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return 0, 0, err
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return size, 0, nil
+	}
+	for _, msg := range msgs {
+		if cred, err := syscall.ParseUnixCredentials(&msg); err == nil {
+			return size, int(cred.Pid), nil
+		}
+	}
+	return size, 0, nil
+}
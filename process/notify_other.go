@@ -0,0 +1,19 @@
+// +build !linux
+// +build !windows
+
+package process
+
+import (
+	"net"
+)
+
+// the credentials of the sender are only passed on linux, the notify socket is protected by its owner and mode
+func setNotifyPassCred(conn *net.UnixConn) error {
+	return nil
+}
+
+// read a message of the notify socket, the pid of the sender is -1 as unknown.
+func readNotifyMsg(conn *net.UnixConn, buf []byte) (int, int, error) {
+	size, err := conn.Read(buf)
+	return size, -1, err
+}
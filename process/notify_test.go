@@ -0,0 +1,86 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the program of TestNotifyReady, it sends READY=1 by the NOTIFY_SOCKET after the SUPD_NOTIFY_GO file exists
+func TestNotifyHelper(t *testing.T) {
+	if os.Getenv("SUPD_NOTIFY_HELPER") != "1" {
+		return
+	}
+	for {
+		if _, err := os.Stat(os.Getenv("SUPD_NOTIFY_GO")); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: os.Getenv("NOTIFY_SOCKET"), Net: "unixgram"})
+	if err != nil {
+		os.Exit(1)
+	}
+	conn.Write([]byte("READY=1\nSTATUS=serving requests"))
+	conn.Close()
+	time.Sleep(30 * time.Second)
+	os.Exit(0)
+}
+
+func TestNotifyReady(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:notify]
+command=`+os.Args[0]+` -test.run=^TestNotifyHelper$
+environment=SUPD_NOTIFY_HELPER="1",SUPD_NOTIFY_GO="%(here)s/go"
+notify=true
+notify_timeout=5
+startsecs=1
+`)
+	proc.notifyDir = dir
+	proc.Start(false)
+	defer proc.Stop(true)
+
+	time.Sleep(500 * time.Millisecond)
+	proc.lock.RLock()
+	path := proc.notify.path
+	proc.lock.RUnlock()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expect the mode of the notify socket is 0600, but got %v %v", info, err)
+	}
+	// the message of the other process is ignored
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("READY=1")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1000 * time.Millisecond)
+	if proc.GetState() != STARTING {
+		t.Fatalf("expect the program is not ready, but it is %s", proc.GetState())
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "go"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20 && proc.GetState() != RUNNING; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if proc.GetState() != RUNNING {
+		t.Fatalf("expect the program is ready, but it is %s", proc.GetState())
+	}
+	if desc := proc.GetDescription(); !strings.HasSuffix(desc, ", serving requests") {
+		t.Errorf("the status is not in the description: %s", desc)
+	}
+}
@@ -0,0 +1,42 @@
+package process
+
+import (
+	"os"
+	"time"
+)
+
+// the sd_notify protocol is not supported on windows
+type notifySocket struct{}
+
+func (n *notifySocket) detach() {
+}
+
+func (p *Process) isNotifyEnabled() bool {
+	return false
+}
+
+func (p *Process) isNotifyReady() bool {
+	return false
+}
+
+func (p *Process) getNotifyTimeout() time.Duration {
+	return 0
+}
+
+func (p *Process) setNotify() error {
+	return nil
+}
+
+func (p *Process) openNotify() error {
+	return nil
+}
+
+func (p *Process) closeNotify() {
+}
+
+func (p *Process) monitorProgramIsReady(endTime time.Time, monitorExited *int32, programExited *int32) {
+	p.monitorProgramIsRunning(endTime, monitorExited, programExited)
+}
+
+func (p *Process) monitorWatchdog(proc *os.Process) {
+}
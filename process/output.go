@@ -0,0 +1,75 @@
+package process
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/gwaycc/supd/events"
+	"github.com/gwaycc/supd/logger"
+	log "github.com/sirupsen/logrus"
+)
+
+// get the timeout of the output, the program is silent if it writes nothing
+// to stdout and stderr in output_timeout seconds while it is RUNNING.
+func (p *Process) getOutputTimeout() time.Duration {
+	return time.Duration(p.config.GetInt("output_timeout", 0)) * time.Second
+}
+
+// get the action applied to the silent program
+//
+//  output_timeout_action=restart|event
+func (p *Process) getOutputTimeoutAction() string {
+	return p.config.GetString("output_timeout_action", "restart")
+}
+
+// timestamp the writes of the stdout and stderr loggers, call with lock.
+func (p *Process) setOutputActivity() {
+	if p.getOutputTimeout() <= 0 {
+		return
+	}
+	stdout := logger.NewActivityLogger(p.StdoutLog, p.lastOutput)
+	if p.StderrLog == p.StdoutLog {
+		p.StderrLog = stdout
+	} else {
+		p.StderrLog = logger.NewActivityLogger(p.StderrLog, p.lastOutput)
+	}
+	p.StdoutLog = stdout
+}
+
+// restart the program or emit the PROCESS_OUTPUT_TIMEOUT event if the program is silent in output_timeout.
+//
+// The PAUSED program is not checked, and the silence is counted from it is RUNNING again.
+func (p *Process) monitorOutput(proc *os.Process) {
+	timeout := p.getOutputTimeout()
+	if timeout <= 0 {
+		return
+	}
+	reported := int64(0)
+	for {
+		time.Sleep(time.Second)
+		p.lock.RLock()
+		// the time is reset under lock when the program is RUNNING
+		lastOutput := atomic.LoadInt64(p.lastOutput)
+		current := !p.detached && p.cmd != nil && p.cmd.Process == proc && (p.state == STARTING || p.state == RUNNING || p.state == PAUSED)
+		silence := time.Now().Sub(time.Unix(0, lastOutput))
+		expired := p.state == RUNNING && silence > timeout
+		p.lock.RUnlock()
+		if !current {
+			return
+		}
+		if !expired || lastOutput == reported {
+			continue
+		}
+		if p.getOutputTimeoutAction() == "event" {
+			log.WithFields(log.Fields{"program": p.GetName(), "pid": proc.Pid}).Warn("the program writes nothing in output_timeout")
+			events.EmitEvent(events.CreateProcessOutputTimeoutEvent(p.GetName(), p.config.GetGroupName(), proc.Pid, int(silence.Seconds())))
+			// report once until the program writes again
+			reported = lastOutput
+			continue
+		}
+		log.WithFields(log.Fields{"program": p.GetName(), "pid": proc.Pid}).Warn("the program writes nothing in output_timeout, restart it")
+		p.Restart(false)
+		return
+	}
+}
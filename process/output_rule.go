@@ -0,0 +1,149 @@
+package process
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gwaycc/supd/events"
+	"github.com/gwaycc/supd/logger"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the rule applies the action when an output line of the program matches the pattern, it is configured by
+// the on_output key or the on_output.<name> keys:
+//
+//  on_output=/OutOfMemoryError/ -> restart
+//  on_output.gc=/GC overhead/ -> signal:USR1
+//  on_output.deadlock=/deadlock/ -> event
+//  on_output.notify=/panic:/ -> exec:/usr/local/bin/notify
+//
+// The action of a rule is applied at most once in on_output_interval seconds.
+type outputRule struct {
+	key     string
+	pattern *regexp.Regexp
+	action  string
+	signal  os.Signal
+	command string
+
+	lock     sync.Mutex
+	lastTime time.Time
+}
+
+// true if the action can be applied now, the time is recorded.
+func (r *outputRule) allow(interval time.Duration) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.lastTime.IsZero() && time.Now().Sub(r.lastTime) < interval {
+		return false
+	}
+	r.lastTime = time.Now()
+	return true
+}
+
+// parse the rule like /pattern/ -> action
+func parseOutputRule(key string, value string) (*outputRule, error) {
+	pos := strings.LastIndex(value, "->")
+	if pos < 0 {
+		return nil, errors.New("the action of on_output is not found").As(key, value)
+	}
+	pattern := strings.TrimSpace(value[:pos])
+	if len(pattern) < 2 || !strings.HasPrefix(pattern, "/") || !strings.HasSuffix(pattern, "/") {
+		return nil, errors.New("the pattern of on_output must be like /regexp/").As(key, value)
+	}
+	re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+	if err != nil {
+		return nil, errors.As(err, key, value)
+	}
+	rule := &outputRule{key: key, pattern: re, action: strings.TrimSpace(value[pos+2:])}
+	switch {
+	case rule.action == "restart" || rule.action == "event":
+	case strings.HasPrefix(rule.action, "signal:"):
+		rule.signal, err = toSignal(strings.TrimPrefix(rule.action, "signal:"))
+		if err != nil {
+			return nil, errors.As(err, key, value)
+		}
+	case strings.HasPrefix(rule.action, "exec:"):
+		rule.command = strings.TrimSpace(strings.TrimPrefix(rule.action, "exec:"))
+		if rule.command == "" {
+			return nil, errors.New("the command of on_output is empty").As(key, value)
+		}
+	default:
+		return nil, errors.New("unsupported on_output action").As(key, value)
+	}
+	return rule, nil
+}
+
+// get the on_output rules of the program in the order of the keys, the bad rules are skipped.
+func (p *Process) getOutputRules() []*outputRule {
+	keys := []string{}
+	for key := range p.config.KeyValues() {
+		if key == "on_output" || strings.HasPrefix(key, "on_output.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	rules := []*outputRule{}
+	for _, key := range keys {
+		// the raw value, the pattern may contain the % of the expression
+		rule, err := parseOutputRule(key, p.config.KeyValues()[key])
+		if err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Error("invalid on_output rule:", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// evaluate the on_output rules on the lines of stdout and stderr, call with lock.
+func (p *Process) setOutputRules() {
+	if !p.config.IsProgram() {
+		return
+	}
+	rules := p.getOutputRules()
+	if len(rules) == 0 {
+		return
+	}
+	interval := time.Duration(p.config.GetInt("on_output_interval", 60)) * time.Second
+	handler := func(line string) {
+		for _, rule := range rules {
+			if rule.pattern.MatchString(line) && rule.allow(interval) {
+				// the logger is in the output pipe of the program, don't block it
+				go p.applyOutputRule(rule, line)
+			}
+		}
+	}
+	stdout := logger.NewLineLogger(p.StdoutLog, handler)
+	if p.StderrLog == p.StdoutLog {
+		p.StderrLog = stdout
+	} else {
+		p.StderrLog = logger.NewLineLogger(p.StderrLog, handler)
+	}
+	p.StdoutLog = stdout
+}
+
+// apply the action of the matched rule
+func (p *Process) applyOutputRule(rule *outputRule, line string) {
+	pid := p.GetPid()
+	log.WithFields(log.Fields{"program": p.GetName(), "pid": pid, "rule": rule.key, "action": rule.action}).Warn("the output of program matches the rule")
+	switch {
+	case rule.action == "restart":
+		p.Restart(false)
+	case rule.action == "event":
+		events.EmitEvent(events.CreateProcessOutputMatchedEvent(p.GetName(), p.config.GetGroupName(), pid, rule.key, line))
+	case rule.signal != nil:
+		if err := p.Signal(rule.signal, p.config.GetBool("stopasgroup", false)); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName(), "rule": rule.key}).Warn("fail to signal the program:", err)
+		}
+	default:
+		timeout := time.Duration(p.config.GetInt("on_output_exec_timeout", 30)) * time.Second
+		if err := p.runCommandLine(rule.key, rule.command, timeout, "SUPD_OUTPUT_RULE="+rule.key, "SUPD_OUTPUT_LINE="+line); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName(), "rule": rule.key}).Warn("fail to run the command of on_output:", err)
+		}
+	}
+}
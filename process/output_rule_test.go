@@ -0,0 +1,87 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOutputRule(t *testing.T) {
+	cases := []struct {
+		value  string
+		action string
+		ok     bool
+	}{
+		{"/OutOfMemoryError/ -> restart", "restart", true},
+		{"/a -> b/ -> event", "event", true},
+		{"/GC/->signal:USR1", "signal:USR1", true},
+		{"/panic:/ -> exec:/usr/local/bin/notify --now", "exec:/usr/local/bin/notify --now", true},
+		{"/x/ -> signal:NOSUCH", "", false},
+		{"/x/ -> exec:", "", false},
+		{"/x/ -> reboot", "", false},
+		{"x -> restart", "", false},
+		{"/(/ -> restart", "", false},
+		{"/x/", "", false},
+	}
+	for _, c := range cases {
+		rule, err := parseOutputRule("on_output", c.value)
+		if (err == nil) != c.ok {
+			t.Fatalf("%s: expect ok %t, but got %v", c.value, c.ok, err)
+		}
+		if err == nil && rule.action != c.action {
+			t.Fatalf("%s: expect action %s, but got %s", c.value, c.action, rule.action)
+		}
+	}
+
+	rule, _ := parseOutputRule("on_output", "/a -> b/ -> event")
+	if !rule.pattern.MatchString("xa -> by") || !rule.allow(time.Minute) || rule.allow(time.Minute) {
+		t.Fatal("expect the rule matches and is applied once in the interval")
+	}
+}
+
+func TestOutputRuleExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-output-rule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "prog.sh")
+	if err := ioutil.WriteFile(script, []byte("echo starting\necho 'java.lang.OutOfMemoryError: heap'\necho 'java.lang.OutOfMemoryError: heap'\nexec sleep 30\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	notify := filepath.Join(dir, "notify.sh")
+	if err := ioutil.WriteFile(notify, []byte("echo \"$SUPD_OUTPUT_RULE $SUPD_OUTPUT_LINE\" >> "+dir+"/matched\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	proc := newTestProcess(t, dir, `[program:rule]
+command=/bin/sh `+script+`
+startsecs=0
+stdout_logfile=`+dir+`/rule.log
+on_output.oom=/OutOfMemoryError/ -> exec:/bin/sh `+notify+`
+on_output.other=/NotPrinted/ -> restart
+`)
+	proc.Start(true)
+	defer proc.Stop(true)
+
+	var data []byte
+	for i := 0; i < 50; i++ {
+		if data, err = ioutil.ReadFile(filepath.Join(dir, "matched")); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	// the second match is limited by on_output_interval
+	time.Sleep(200 * time.Millisecond)
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "matched"))
+	if strings.TrimSpace(string(data)) != "on_output.oom java.lang.OutOfMemoryError: heap" {
+		t.Fatalf("unexpected output of the on_output command: %q", data)
+	}
+	if proc.GetState() != RUNNING {
+		t.Fatalf("expect the program is running, but it is %s", proc.GetState())
+	}
+}
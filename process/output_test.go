@@ -0,0 +1,53 @@
+// +build linux
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOutputTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:output]
+command=/bin/sleep 30
+startsecs=0
+stdout_logfile=`+dir+`/output.log
+output_timeout=1
+`)
+	proc.Start(true)
+	defer proc.Stop(true)
+	pid := proc.GetPid()
+
+	// the paused program is not silent
+	if err := proc.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * time.Second)
+	if proc.GetPid() != pid {
+		t.Fatal("expect the paused program is not restarted")
+	}
+	if err := proc.Resume(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the silence is counted from the program is resumed
+	time.Sleep(500 * time.Millisecond)
+	if proc.GetPid() != pid {
+		t.Fatal("expect the resumed program is not restarted at once")
+	}
+
+	// the silent program is restarted
+	for i := 0; i < 100 && (proc.GetPid() == pid || proc.GetPid() == 0 || proc.GetState() != RUNNING); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if proc.GetPid() == pid || proc.GetState() != RUNNING {
+		t.Fatalf("expect the silent program is restarted, but it is %s", proc.GetState())
+	}
+}
@@ -0,0 +1,63 @@
+package process
+
+import (
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// freeze the running program, the paused program is alive and it is not restarted.
+func (p *Process) Pause() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state != RUNNING || p.cmd == nil || p.cmd.Process == nil {
+		return errors.New("the program is not running").As(p.GetName(), p.state.String())
+	}
+	if err := p.freeze(true); err != nil {
+		return errors.As(err, p.GetName())
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("pause the program")
+	p.changeStateTo(PAUSED)
+	return nil
+}
+
+// thaw the paused program
+func (p *Process) Resume() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state != PAUSED {
+		return errors.New("the program is not paused").As(p.GetName(), p.state.String())
+	}
+	if err := p.freeze(false); err != nil {
+		return errors.As(err, p.GetName())
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("resume the program")
+	p.changeStateTo(RUNNING)
+	return nil
+}
+
+// put the program into the freezer cgroup created by supd, the program is paused by
+// SIGSTOP if the cgroup is not available. Call with lock.
+//
+// The running process is moved only if move is true, or the cgroup is used if the process is in it already.
+func (p *Process) attachCgroup(pid int, move bool) {
+	p.cgroup = nil
+	if !p.config.GetBool("pause_cgroup", true) {
+		return
+	}
+	cgroup, err := getFreezerCgroup(p.GetName())
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Debug("the cgroup freezer is not available:", err)
+		return
+	}
+	if !cgroup.contains(pid) {
+		if !move {
+			return
+		}
+		if err := cgroup.attach(pid); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Debug("fail to create the cgroup of the program:", err)
+			cgroup.remove()
+			return
+		}
+	}
+	p.cgroup = cgroup
+}
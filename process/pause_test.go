@@ -0,0 +1,139 @@
+// +build linux
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// true if the process is stopped by the signal, the state in /proc/<pid>/stat is T
+func isProcStopped(t *testing.T, pid int) bool {
+	// the signal is delivered asynchronously
+	for i := 0; i < 10; i++ {
+		data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat := string(data)
+		if strings.Fields(stat[strings.LastIndex(stat, ")")+1:])[0] == "T" {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPauseResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:pause]
+command=/bin/sleep 30
+startsecs=0
+stopsignal=TERM
+pause_cgroup=false
+`)
+	if err := proc.Pause(); err == nil {
+		t.Fatal("expect the program is not running")
+	}
+	proc.Start(true)
+	defer proc.Stop(true)
+	pid := proc.GetPid()
+
+	if err := proc.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if proc.GetState() != PAUSED || proc.Stoped() || !isProcStopped(t, pid) {
+		t.Fatalf("expect the program is paused, but it is %s", proc.GetState())
+	}
+	if err := proc.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if proc.GetState() != RUNNING || isProcStopped(t, pid) {
+		t.Fatalf("expect the program is resumed, but it is %s", proc.GetState())
+	}
+
+	// the paused program is resumed to handle the stop signal
+	if err := proc.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	proc.Stop(true)
+	if !proc.Stoped() {
+		t.Errorf("expect the paused program is stopped, but it is %s", proc.GetState())
+	}
+}
+
+func TestPauseCgroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the child forked by the program is frozen too
+	if err := ioutil.WriteFile(filepath.Join(dir, "tree.sh"), []byte("/bin/sleep 30 &\nwait\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	proc := newTestProcess(t, dir, `[program:pause]
+command=/bin/sh %(here)s/tree.sh
+startsecs=0
+stopsignal=TERM
+stopasgroup=true
+`)
+	proc.Start(true)
+	defer proc.Stop(true)
+	cgroup := proc.cgroup
+	if cgroup == nil {
+		t.Skip("the cgroup freezer is not writable")
+	}
+	pid := proc.GetPid()
+	var child int
+	for i := 0; i < 10 && child == 0; i++ {
+		data, _ := ioutil.ReadFile(fmt.Sprintf("/proc/%d/task/%d/children", pid, pid))
+		fmt.Sscan(string(data), &child)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if child == 0 || !cgroup.contains(child) {
+		t.Fatalf("expect the child %d is in the cgroup %s", child, cgroup.dir)
+	}
+
+	if err := proc.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if proc.GetState() != PAUSED || !cgroup.isFrozen() {
+		t.Fatalf("expect the cgroup is frozen, but the program is %s", proc.GetState())
+	}
+	// no stop signal is seen by the program and its parent
+	if isProcStopped(t, pid) {
+		t.Fatal("expect the program is frozen by the cgroup instead of SIGSTOP")
+	}
+	if err := proc.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if proc.GetState() != RUNNING || cgroup.isFrozen() {
+		t.Fatalf("expect the cgroup is thawed, but the program is %s", proc.GetState())
+	}
+
+	// the frozen program is thawed to be stopped, and the cgroup is removed after it exits
+	if err := proc.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	proc.Stop(true)
+	if !proc.Stoped() {
+		t.Fatalf("expect the paused program is stopped, but it is %s", proc.GetState())
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := os.Stat(cgroup.dir); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Errorf("expect the cgroup %s is removed", cgroup.dir)
+}
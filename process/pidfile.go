@@ -0,0 +1,140 @@
+package process
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// true if the command of the program forks the daemon and exits, the daemon is found by the pidfile.
+func (p *Process) isDaemonizes() bool {
+	return p.config.IsProgram() && p.config.GetBool("daemonizes", false)
+}
+
+// get the pidfile written by the daemon, the relative path is relative to the directory of the program.
+func (p *Process) getPidfile() string {
+	pidfile := p.config.GetStringExpression("pidfile", "")
+	if pidfile == "" || filepath.IsAbs(pidfile) {
+		return pidfile
+	}
+	dir := p.getDir()
+	if dir == "" {
+		dir = p.config.ConfigDir
+	}
+	return filepath.Join(dir, pidfile)
+}
+
+// redirect the output of the launcher to the pipe files, call with lock.
+//
+// The daemon may inherit the output of the launcher, the output is copied until the daemon exits.
+func (p *Process) setDaemonLog() error {
+	stdout, err := p.createLogPipe(p.StdoutLog)
+	if err != nil {
+		return errors.As(err)
+	}
+	p.cmd.Stdout = stdout
+	if p.StderrLog == p.StdoutLog {
+		p.cmd.Stderr = stdout
+		return nil
+	}
+	stderr, err := p.createLogPipe(p.StderrLog)
+	if err != nil {
+		return errors.As(err)
+	}
+	p.cmd.Stderr = stderr
+	return nil
+}
+
+// create the pipe which is copied to the logger and return the writer of it, call with lock.
+func (p *Process) createLogPipe(out io.Writer) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	go func() {
+		// keep reading after the logger is closed, or the daemon blocks on writing
+		io.Copy(out, r)
+		io.Copy(ioutil.Discard, r)
+		r.Close()
+	}()
+	p.closeAfterStart = append(p.closeAfterStart, w)
+	return w, nil
+}
+
+// wait the launcher exits and the daemon writes the pidfile, call without lock.
+//
+// The pidfile must be written after the start time, a stale pidfile is ignored.
+func (p *Process) waitDaemonized(launcher *exec.Cmd, startTime time.Time) (*ChildState, error) {
+	pidfile := p.getPidfile()
+	if pidfile == "" {
+		return nil, errors.New("the pidfile of daemonizes program is not configured").As(p.GetName())
+	}
+	// the output of the launcher is the pipe files, the Cmd.Wait does not wait the daemon which inherits them.
+	if err := launcher.Wait(); err != nil {
+		return nil, errors.New("the launcher of daemonizes program fails").As(p.GetName(), err)
+	}
+
+	timeout := time.Duration(p.config.GetInt("pidfile_timeout", 10)) * time.Second
+	endTime := time.Now().Add(timeout)
+	for {
+		pid, err := readPidfile(pidfile, startTime)
+		if err == nil {
+			fingerprint, err := procFingerprint(pid)
+			if err != nil {
+				return nil, errors.As(err, pidfile)
+			}
+			log.WithFields(log.Fields{"program": p.GetName(), "pid": pid}).Info("the program is daemonized")
+			return &ChildState{Name: p.GetName(), Pid: pid, StartTime: startTime.Unix(), Fingerprint: fingerprint}, nil
+		}
+		if !time.Now().Before(endTime) {
+			return nil, errors.New("the pidfile is not written in time").As(pidfile, timeout, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// read the pid in the pidfile which is written after the start time.
+func readPidfile(pidfile string, startTime time.Time) (int, error) {
+	info, err := os.Stat(pidfile)
+	if err != nil {
+		return 0, errors.As(err, pidfile)
+	}
+	// the mtime may be truncated to seconds by the file system
+	if info.ModTime().Before(startTime.Truncate(time.Second)) {
+		return 0, errors.New("the pidfile is stale").As(pidfile)
+	}
+	data, err := ioutil.ReadFile(pidfile)
+	if err != nil {
+		return 0, errors.As(err, pidfile)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, errors.New("invalid pid in pidfile").As(pidfile, string(data))
+	}
+	return pid, nil
+}
+
+// supervise the daemon instead of the exited launcher, call with lock.
+func (p *Process) attachDaemon(daemon *ChildState) error {
+	proc, err := os.FindProcess(daemon.Pid)
+	if err != nil {
+		return errors.As(err, daemon.Pid)
+	}
+	p.cmd = &exec.Cmd{Path: p.cmd.Path, Args: p.cmd.Args, Process: proc}
+	p.adopted = daemon
+	if p.StdoutLog != nil {
+		p.StdoutLog.SetPid(daemon.Pid)
+	}
+	if p.StderrLog != nil {
+		p.StderrLog.SetPid(daemon.Pid)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// +build linux
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemonizes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-pidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// a stale pidfile is ignored
+	pidfile := filepath.Join(dir, "daemon.pid")
+	if err := ioutil.WriteFile(pidfile, []byte("1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-time.Hour)
+	os.Chtimes(pidfile, stale, stale)
+	script := filepath.Join(dir, "daemon.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsleep 30 &\necho $! > daemon.pid\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	proc := newTestProcess(t, dir, `[program:daemon]
+command=`+script+`
+directory=`+dir+`
+daemonizes=true
+pidfile=daemon.pid
+startsecs=1
+stopsignal=TERM
+`)
+	proc.Start(true)
+	if proc.GetState() != RUNNING {
+		t.Fatalf("expect the daemon is running, but it is %s", proc.GetState())
+	}
+	data, err := ioutil.ReadFile(pidfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	if pid <= 1 || proc.GetPid() != pid {
+		t.Fatalf("expect the pid of the daemon is %d, but it is %d", pid, proc.GetPid())
+	}
+	proc.Stop(true)
+	if _, err := procFingerprint(pid); proc.GetState() == RUNNING || err == nil {
+		t.Errorf("expect the daemon is stopped, the state is %s", proc.GetState())
+	}
+}
@@ -0,0 +1,116 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// the error of the spawn precondition, it is reported as the spawnerr of the program.
+type spawnError struct {
+	msg string
+	// true if the error may be gone by retrying, e.g. the port is released later
+	retry bool
+}
+
+func (e *spawnError) Error() string {
+	return e.msg
+}
+
+// get the declared ports of the program, the port without host is on all the interfaces.
+//
+//  ports=8080,127.0.0.1:9090,[::1]:7000
+func (p *Process) getPorts() []string {
+	ports := strings.FieldsFunc(p.config.GetStringExpression("ports", ""), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	for i, port := range ports {
+		if _, err := strconv.Atoi(port); err == nil {
+			ports[i] = ":" + port
+		}
+	}
+	return ports
+}
+
+// GetPorts get the port allocated by port_range and the declared ports of the program
+func (p *Process) GetPorts() []string {
+	ports := p.getPorts()
+	if port := p.config.GetString("port", ""); port != "" {
+		for _, declared := range ports {
+			if declared == ":"+port {
+				return ports
+			}
+		}
+		ports = append([]string{":" + port}, ports...)
+	}
+	return ports
+}
+
+// check the directory, the executable and the ports of the program before spawning it, call without lock.
+func (p *Process) checkSpawn() *spawnError {
+	dir := p.getDir()
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return &spawnError{msg: fmt.Sprintf("the directory %s does not exist", dir)}
+		}
+	}
+
+	args, err := parseCommand(p.config.GetStringExpression("command", ""))
+	if err != nil {
+		return &spawnError{msg: fmt.Sprintf("bad command: %v", err)}
+	}
+	if err := checkExecutable(args[0], dir); err != nil {
+		return err
+	}
+
+	for _, addr := range p.getPorts() {
+		ln, err := net.Listen("tcp", addr)
+		if err == nil {
+			ln.Close()
+			continue
+		}
+		msg := fmt.Sprintf("the port %s is in use", addr)
+		if pid := findPortHolder(addr); pid > 0 {
+			msg = fmt.Sprintf("%s by pid %d", msg, pid)
+			if comm := procComm(pid); comm != "" {
+				msg = fmt.Sprintf("%s (%s)", msg, comm)
+			}
+			if p.procMgr != nil {
+				if holder := p.procMgr.FindByPid(pid); holder != nil {
+					msg = fmt.Sprintf("%s of program %s", msg, holder.GetName())
+				}
+			}
+		}
+		return &spawnError{msg: msg, retry: true}
+	}
+	return nil
+}
+
+// check the command resolves to an executable file, the relative path is relative to the directory.
+func checkExecutable(command string, dir string) *spawnError {
+	path := command
+	if !strings.ContainsRune(command, os.PathSeparator) {
+		found, err := exec.LookPath(command)
+		if err != nil {
+			return &spawnError{msg: fmt.Sprintf("the command %s is not found in PATH", command)}
+		}
+		path = found
+	} else if !filepath.IsAbs(command) && dir != "" {
+		path = filepath.Join(dir, command)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return &spawnError{msg: fmt.Sprintf("the command %s does not exist", path)}
+	}
+	// the executable bits are not used on windows
+	if info.IsDir() || (info.Mode()&0111 == 0 && runtime.GOOS != "windows") {
+		return &spawnError{msg: fmt.Sprintf("the command %s is not an executable file", path)}
+	}
+	return nil
+}
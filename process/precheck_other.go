@@ -0,0 +1,12 @@
+// +build !linux
+
+package process
+
+// the holder of the port is only found on linux
+func findPortHolder(addr string) int {
+	return 0
+}
+
+func procComm(pid int) string {
+	return ""
+}
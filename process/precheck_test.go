@@ -0,0 +1,74 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckSpawn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-precheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "noexec.sh"), []byte("exit 0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	cases := []struct {
+		program  string
+		spawnErr string
+		retry    bool
+	}{
+		{"command=/bin/cat\ndirectory=" + dir, "", false},
+		{"command=cat", "", false},
+		{"command=/bin/cat\ndirectory=" + dir + "/missing", "the directory " + dir + "/missing does not exist", false},
+		{"command=no-such-command-x", "the command no-such-command-x is not found in PATH", false},
+		{"command=/no/such/command", "the command /no/such/command does not exist", false},
+		{"command=./noexec.sh\ndirectory=" + dir, "the command " + dir + "/noexec.sh is not an executable file", false},
+		{"command=/bin/cat\nports=127.0.0.1:" + port, "the port 127.0.0.1:" + port + " is in use by pid " + strconv.Itoa(os.Getpid()), true},
+	}
+	for i, c := range cases {
+		proc := newTestProcess(t, dir, "[program:precheck]\n"+c.program+"\n")
+		err := proc.checkSpawn()
+		if c.spawnErr == "" {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), c.spawnErr) || err.retry != c.retry {
+			t.Fatalf("case %d: expect %q, but got %v", i, c.spawnErr, err)
+		}
+	}
+}
+
+func TestSpawnErr(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-precheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:precheck]
+command=/bin/cat
+directory=`+dir+`/missing
+`)
+	proc.Start(true)
+	if proc.GetState() != FATAL || proc.GetSpawnErr() == "" || proc.GetDescription() != proc.GetSpawnErr() {
+		t.Fatalf("expect the program is FATAL with the spawnerr, but it is %s: %s", proc.GetState(), proc.GetDescription())
+	}
+}
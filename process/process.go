@@ -31,6 +31,8 @@ const (
 	RUNNING               = 20
 	BACKOFF               = 30
 	STOPPING              = 40
+	PAUSED                = 50
+	STANDBY               = 60
 	EXITED                = 100
 	FATAL                 = 200
 	UNKNOWN               = 1000
@@ -48,6 +50,10 @@ func (p ProcessState) String() string {
 		return "BACKOFF"
 	case STOPPING:
 		return "STOPPING"
+	case PAUSED:
+		return "PAUSED"
+	case STANDBY:
+		return "STANDBY"
 	case EXITED:
 		return "EXITED"
 	case FATAL:
@@ -73,6 +79,49 @@ type Process struct {
 	stdin      io.WriteCloser
 	StdoutLog  logger.Logger
 	StderrLog  logger.Logger
+
+	// the directory of the log fifos, the child is adoptable if it is not empty
+	adoptDir string
+	// the child to be adopted by the next run
+	adopting *ChildState
+	// the adopted child which is running now
+	adopted *ChildState
+	// the freezer cgroup created by supd for the program, nil if the program is not in it
+	cgroup *freezerCgroup
+	// the child is left to the next supervisor in the same supd
+	detached        bool
+	logFifos        []*logFifo
+	closeAfterStart []*os.File
+
+	// the watch of the program files, it is active from the start to the stop of the program
+	watch *fileWatch
+
+	// the program can't be started if it is disabled by the user
+	disabled bool
+	// the reason why the program is skipped by its conditions
+	skipReason string
+
+	// the directory of the notify sockets, the sd_notify protocol is supported if it is not empty
+	notifyDir       string
+	notify          *notifySocket
+	notifyReady     bool
+	notifyStatus    string
+	notifyStopping  bool
+	notifyMainPid   int
+	lastWatchdog    time.Time
+	watchdogTrigger bool
+
+	// the time of the last output in unix nanoseconds, it is updated by the loggers
+	lastOutput *int64
+
+	// the manager of the process, it finds the program holding the port
+	procMgr *ProcessManager
+	// the reason why the last spawn failed, it is cleared after the program is spawned
+	spawnErr string
+
+	// the held singleton lock, and the cancel of waiting for the lock in STANDBY
+	singleton     SingletonLock
+	standbyCancel chan struct{}
 }
 
 func NewProcess(supervisor_id string, config *config.ConfigEntry) *Process {
@@ -84,7 +133,8 @@ func NewProcess(supervisor_id string, config *config.ConfigEntry) *Process {
 		state:      STOPPED,
 		inStart:    false,
 		stopByUser: false,
-		retryTimes: new(int32)}
+		retryTimes: new(int32),
+		lastOutput: new(int64)}
 	proc.config = config
 	proc.cmd = nil
 	return proc
@@ -96,6 +146,14 @@ func (p *Process) GetConfig() *config.ConfigEntry {
 
 func (p *Process) SetConfig(entry *config.ConfigEntry) {
 	p.config = entry
+
+	// the watched files may be changed
+	p.lock.RLock()
+	watching := p.watch != nil || (p.inStart && !p.stopByUser)
+	p.lock.RUnlock()
+	if watching {
+		p.startWatch()
+	}
 }
 
 // start the process
@@ -109,11 +167,63 @@ func (p *Process) Start(wait bool) {
 		p.lock.Unlock()
 		return
 	}
+	if p.disabled {
+		log.WithFields(log.Fields{"program": p.GetName()}).Info("Don't start program, program is disabled")
+		p.lock.Unlock()
+		return
+	}
+	if p.detached {
+		log.WithFields(log.Fields{"program": p.GetName()}).Info("Don't start program, program is detached")
+		p.lock.Unlock()
+		return
+	}
 
 	p.inStart = true
 	p.stopByUser = false
+	adopting := p.adopting != nil
 	p.lock.Unlock()
 
+	// the adopted child is running, its conditions were met.
+	// the condition_command may take a while, check without lock.
+	if !adopting {
+		err := p.CheckConditions()
+		p.lock.Lock()
+		p.skipReason = ""
+		if err != nil {
+			p.skipReason = err.Error()
+			p.inStart = false
+		}
+		p.lock.Unlock()
+		if err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Info("Don't start program, the condition is not met: ", err)
+			return
+		}
+	}
+
+	// the program runs only on the host holding the singleton lock
+	if !p.tryAcquireSingleton() {
+		if adopting {
+			p.killAdopting()
+		}
+		cancel := p.enterStandby()
+		go func() {
+			if p.waitSingleton(cancel) {
+				p.startLoop(false)
+				return
+			}
+			p.lock.Lock()
+			p.inStart = false
+			p.lock.Unlock()
+		}()
+		return
+	}
+	p.startLoop(wait)
+}
+
+// start the program and restart it by autorestart
+func (p *Process) startLoop(wait bool) {
+	p.startWatch()
+
 	var runCond *sync.Cond
 	finished := false
 	if wait {
@@ -152,6 +262,10 @@ func (p *Process) Start(wait bool) {
 				break
 			}
 		}
+		if p.StopedByUser() {
+			p.closeNotify()
+		}
+		p.releaseSingleton()
 		p.lock.Lock()
 		p.inStart = false
 		p.lock.Unlock()
@@ -179,15 +293,34 @@ func (p *Process) GetGroup() string {
 func (p *Process) GetDescription() string {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
-	if p.state == RUNNING {
+	if p.state == RUNNING || p.state == PAUSED {
 		seconds := int(time.Now().Sub(p.startTime).Seconds())
 		minutes := seconds / 60
 		hours := minutes / 60
 		days := hours / 24
+		pid := fmt.Sprintf("pid %d", p.cmd.Process.Pid)
+		if p.notifyMainPid > 0 && p.notifyMainPid != p.cmd.Process.Pid {
+			pid = fmt.Sprintf("%s (main pid %d)", pid, p.notifyMainPid)
+		}
+		desc := fmt.Sprintf("%s, uptime %d:%02d:%02d", pid, hours%24, minutes%60, seconds%60)
 		if days > 0 {
-			return fmt.Sprintf("pid %d, uptime %d days, %d:%02d:%02d", p.cmd.Process.Pid, days, hours%24, minutes%60, seconds%60)
+			desc = fmt.Sprintf("%s, uptime %d days, %d:%02d:%02d", pid, days, hours%24, minutes%60, seconds%60)
+		}
+		// the status sent by the program through the notify socket
+		if p.notifyStatus != "" {
+			desc = fmt.Sprintf("%s, %s", desc, p.notifyStatus)
 		}
-		return fmt.Sprintf("pid %d, uptime %d:%02d:%02d", p.cmd.Process.Pid, hours%24, minutes%60, seconds%60)
+		return desc
+	} else if p.state == STARTING && p.notifyStatus != "" {
+		return p.notifyStatus
+	} else if p.state == STANDBY {
+		return "waiting for the singleton lock " + p.getSingletonLock()
+	} else if p.disabled && p.state != STARTING && p.state != STOPPING {
+		return "disabled"
+	} else if p.spawnErr != "" && (p.state == BACKOFF || p.state == FATAL) {
+		return p.spawnErr
+	} else if p.skipReason != "" && p.state != STARTING && p.state != STOPPING {
+		return "SKIPPED: " + p.skipReason
 	} else if p.state != STOPPED {
 		return p.stopTime.Format(time.RFC3339)
 	}
@@ -224,6 +357,8 @@ func (p *Process) GetPid() int {
 
 // Get the process state
 func (p *Process) GetState() ProcessState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
 	return p.state
 }
 
@@ -237,6 +372,8 @@ func (p *Process) GetStopTime() time.Time {
 		fallthrough
 	case RUNNING:
 		fallthrough
+	case PAUSED:
+		fallthrough
 	case STOPPING:
 		return time.Unix(0, 0)
 	default:
@@ -305,6 +442,10 @@ func (p *Process) isAutoRestart() bool {
 	} else {
 		p.lock.RLock()
 		defer p.lock.RUnlock()
+		if p.adopted != nil {
+			// the exit code of the adopted child is unknown
+			return true
+		}
 		if p.cmd != nil && p.cmd.ProcessState != nil {
 			exitCode, err := p.getExitCode()
 			//If unexpected, the process will be restarted when the program exits
@@ -385,23 +526,60 @@ func (p *Process) createProgramCommand() (err error) {
 		p.cmd.Args = args
 	}
 	p.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	p.adopted = nil
 	if p.setUser() != nil {
 		log.WithFields(log.Fields{"user": p.config.GetString("user", "")}).Error("fail to run as user")
 		return fmt.Errorf("fail to set user")
 	}
-	set_deathsig(p.cmd.SysProcAttr)
-	p.setEnv()
+	if p.isAdoptable() {
+		// the child keeps running after supd exits and waits for adopting
+		set_adoptable(p.cmd.SysProcAttr)
+	} else {
+		set_deathsig(p.cmd.SysProcAttr)
+	}
+	if err := p.setEnv(); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error(err)
+		return err
+	}
+	if err := p.setNotify(); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error("fail to open the notify socket:", err)
+		return err
+	}
 	p.setDir()
 	p.setLog()
 
-	p.stdin, _ = p.cmd.StdinPipe()
+	if !p.isAdoptable() {
+		p.stdin, _ = p.cmd.StdinPipe()
+	} else {
+		// the stdin can't be reattached
+		p.stdin = nil
+	}
 	return nil
 
 }
 
 // wait for the started program exit
 func (p *Process) waitForExit(startSecs int64) {
-	err := p.cmd.Wait()
+	var err error
+	if p.adopted != nil {
+		err = p.waitAdopted(p.adopted)
+	} else {
+		err = p.cmd.Wait()
+	}
+	p.lock.Lock()
+	fifos := p.logFifos
+	p.logFifos = nil
+	cgroup := p.cgroup
+	p.cgroup = nil
+	p.lock.Unlock()
+	for _, f := range fifos {
+		f.close()
+	}
+	if cgroup != nil {
+		if err := cgroup.remove(); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Debug("the cgroup of the program is kept:", err)
+		}
+	}
 	if err != nil {
 		log.WithFields(log.Fields{"program": p.GetName()}).Warn("program exited", errors.As(err, p.cmd.Path))
 	} else if p.cmd.ProcessState != nil {
@@ -436,9 +614,19 @@ func (p *Process) monitorProgramIsRunning(endTime time.Time, monitorExited *int3
 	if atomic.LoadInt32(programExited) == 0 && p.state == STARTING {
 		log.WithFields(log.Fields{"program": p.GetName()}).Info("success to start program")
 		p.changeStateTo(RUNNING)
+		p.runHookAsync(HOOK_POST_START)
 	}
 }
 
+// release the files which are opened for starting the program, call with lock.
+func (p *Process) releaseStartFiles() {
+	for _, f := range p.closeAfterStart {
+		f.Close()
+	}
+	p.closeAfterStart = nil
+	p.closeLogFifos()
+}
+
 func (p *Process) run(finishCb func(code int)) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -462,6 +650,23 @@ func (p *Process) run(finishCb func(code int)) {
 			finishCb(code)
 		})
 	}
+	// supervise the adopted child at first
+	if child := p.adopting; child != nil {
+		p.adopting = nil
+		if err := p.adoptProgram(child); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to adopt program", errors.As(err))
+		} else {
+			go finishCbWrapper(0)
+			go p.monitorOutput(p.cmd.Process)
+			p.lock.Unlock()
+			p.waitForExit(startSecs)
+			p.lock.Lock()
+			p.changeStateTo(EXITED)
+			log.WithFields(log.Fields{"program": p.GetName()}).Info("adopted program exited")
+			return
+		}
+	}
+
 	//process is not expired and not stoped by user
 	for !p.stopByUser {
 		if restartPause > 0 && atomic.LoadInt32(p.retryTimes) != 0 {
@@ -475,15 +680,73 @@ func (p *Process) run(finishCb func(code int)) {
 		p.changeStateTo(STARTING)
 		atomic.AddInt32(p.retryTimes, 1)
 
+		// the port holder is found without lock
+		p.lock.Unlock()
+		spawnErr := p.checkSpawn()
+		p.lock.Lock()
+		if spawnErr != nil {
+			p.spawnErr = spawnErr.Error()
+			if !spawnErr.retry || atomic.LoadInt32(p.retryTimes) >= p.getStartRetries() {
+				p.failToStartProgram(fmt.Sprintf("fail to spawn program: %s", spawnErr), finishCbWrapper, -1)
+				break
+			}
+			log.WithFields(log.Fields{"program": p.GetName()}).Info("fail to spawn program: ", spawnErr)
+			p.changeStateTo(BACKOFF)
+			// wait longer for each retry, the port may be released later
+			p.lock.Unlock()
+			time.Sleep(time.Duration(atomic.LoadInt32(p.retryTimes)) * time.Second)
+			p.lock.Lock()
+			continue
+		}
+		if p.stopByUser {
+			log.WithFields(log.Fields{"program": p.GetName()}).Info("the program is stopped by user before spawning")
+			p.changeStateTo(STOPPED)
+			finishCbWrapper(0)
+			break
+		}
+
 		err := p.createProgramCommand()
 		if err != nil {
 			p.failToStartProgram("fail to create program", finishCbWrapper, -1)
 			break
 		}
+		if p.hasHook(HOOK_PRE_START) {
+			p.lock.Unlock()
+			err = p.runHook(HOOK_PRE_START)
+			p.lock.Lock()
+			if err != nil {
+				p.releaseStartFiles()
+				if atomic.LoadInt32(p.retryTimes) >= p.getStartRetries() {
+					p.failToStartProgram(fmt.Sprintf("fail to run the pre_start hook with error:%v", err), finishCbWrapper, -1)
+					break
+				}
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("fail to run the pre_start hook with error:", err)
+				p.changeStateTo(BACKOFF)
+				// wait longer for each retry like the failed spawn
+				p.lock.Unlock()
+				time.Sleep(time.Duration(atomic.LoadInt32(p.retryTimes)) * time.Second)
+				p.lock.Lock()
+				continue
+			}
+			if p.stopByUser {
+				p.releaseStartFiles()
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("the program is stopped by user in the pre_start hook")
+				p.changeStateTo(STOPPED)
+				finishCbWrapper(0)
+				break
+			}
+		}
 
+		launchTime := time.Now()
 		err = p.cmd.Start()
+		for _, f := range p.closeAfterStart {
+			f.Close()
+		}
+		p.closeAfterStart = nil
 
 		if err != nil {
+			p.releaseStartFiles()
+			p.spawnErr = err.Error()
 			if atomic.LoadInt32(p.retryTimes) >= p.getStartRetries() {
 				p.failToStartProgram(fmt.Sprintf("fail to start program with error:%v", errors.As(err)), finishCbWrapper, -1)
 				break
@@ -493,20 +756,57 @@ func (p *Process) run(finishCb func(code int)) {
 				continue
 			}
 		}
+		p.spawnErr = ""
 		if p.StdoutLog != nil {
 			p.StdoutLog.SetPid(p.cmd.Process.Pid)
 		}
 		if p.StderrLog != nil {
 			p.StderrLog.SetPid(p.cmd.Process.Pid)
 		}
+		if p.isDaemonizes() {
+			// supervise the daemon forked by the command
+			launcher := p.cmd
+			p.lock.Unlock()
+			daemon, err := p.waitDaemonized(launcher, launchTime)
+			p.lock.Lock()
+			if err == nil {
+				err = p.attachDaemon(daemon)
+			}
+			if err != nil {
+				if atomic.LoadInt32(p.retryTimes) >= p.getStartRetries() {
+					p.failToStartProgram(fmt.Sprintf("fail to start daemonizes program with error:%v", err), finishCbWrapper, -1)
+					break
+				}
+				log.WithFields(log.Fields{"program": p.GetName()}).Info("fail to start daemonizes program with error:", err)
+				p.changeStateTo(BACKOFF)
+				continue
+			}
+			if p.stopByUser {
+				// the stop signals were sent to the launcher
+				p.sendSignal(syscall.SIGTERM, p.config.GetBool("stopasgroup", false))
+			}
+		}
+
+		p.attachCgroup(p.cmd.Process.Pid, true)
 
 		monitorExited := int32(0)
 		programExited := int32(0)
-		//Set startsec to 0 to indicate that the program needn't stay
-		//running for any particular amount of time.
-		if startSecs <= 0 {
+		go p.monitorWatchdog(p.cmd.Process)
+		go p.monitorOutput(p.cmd.Process)
+		if p.isNotifyReady() {
+			// the program is RUNNING after it sends READY=1
+			go func() {
+				p.monitorProgramIsReady(time.Now().Add(p.getNotifyTimeout()), &monitorExited, &programExited)
+				finishCbWrapper(0)
+			}()
+		} else if startSecs <= 0 {
+			//Set startsec to 0 to indicate that the program needn't stay
+			//running for any particular amount of time.
 			log.WithFields(log.Fields{"program": p.GetName()}).Info("success to start program")
 			p.changeStateTo(RUNNING)
+			p.runHookAsync(HOOK_POST_START)
+			// no monitor is waited
+			atomic.StoreInt32(&monitorExited, 1)
 			go finishCbWrapper(0)
 		} else {
 			go func() {
@@ -527,7 +827,7 @@ func (p *Process) run(finishCb func(code int)) {
 		p.lock.Lock()
 
 		// if the program still in running after startSecs
-		if p.state == RUNNING {
+		if p.state == RUNNING || p.state == PAUSED {
 			p.changeStateTo(EXITED)
 			log.WithFields(log.Fields{"program": p.GetName()}).Info("program exited")
 			break
@@ -555,9 +855,15 @@ func (p *Process) changeStateTo(procState ProcessState) {
 		if procState == STARTING {
 			events.EmitEvent(events.CreateProcessStartingEvent(progName, groupName, p.state.String(), int(atomic.LoadInt32(p.retryTimes))))
 		} else if procState == RUNNING {
+			p.lastWatchdog = time.Now()
+			atomic.StoreInt64(p.lastOutput, time.Now().UnixNano())
 			events.EmitEvent(events.CreateProcessRunningEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
 		} else if procState == BACKOFF {
 			events.EmitEvent(events.CreateProcessBackoffEvent(progName, groupName, p.state.String(), int(atomic.LoadInt32(p.retryTimes))))
+		} else if procState == PAUSED {
+			events.EmitEvent(events.CreateProcessPausedEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
+		} else if procState == STANDBY {
+			events.EmitEvent(events.CreateProcessStandbyEvent(progName, groupName, p.state.String()))
 		} else if procState == STOPPING {
 			events.EmitEvent(events.CreateProcessStoppingEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
 		} else if procState == EXITED {
@@ -570,7 +876,11 @@ func (p *Process) changeStateTo(procState ProcessState) {
 		} else if procState == FATAL {
 			events.EmitEvent(events.CreateProcessFatalEvent(progName, groupName, p.state.String()))
 		} else if procState == STOPPED {
-			events.EmitEvent(events.CreateProcessStoppedEvent(progName, groupName, p.state.String(), p.cmd.Process.Pid))
+			pid := 0
+			if p.cmd != nil && p.cmd.Process != nil {
+				pid = p.cmd.Process.Pid
+			}
+			events.EmitEvent(events.CreateProcessStoppedEvent(progName, groupName, p.state.String(), pid))
 		} else if procState == UNKNOWN {
 			events.EmitEvent(events.CreateProcessUnknownEvent(progName, groupName, p.state.String()))
 		}
@@ -605,73 +915,65 @@ func (p *Process) sendSignal(sig os.Signal, sigChildren bool) error {
 	return fmt.Errorf("process is not started")
 }
 
-func (p *Process) setEnv() {
-	env := p.config.GetEnv("environment")
-	if len(env) != 0 {
-		p.cmd.Env = append(os.Environ(), env...)
-	} else {
-		p.cmd.Env = os.Environ()
+// set the environment of the program, the env_file entries are merged before
+// the environment entries so the later can override them.
+func (p *Process) setEnv() error {
+	env, err := p.getEnv()
+	if err != nil {
+		return errors.As(err)
 	}
+	p.cmd.Env = env
+	return nil
 }
 
-func (p *Process) setDir() {
-	dir := p.config.GetStringExpression("directory", "")
-	if dir != "" {
-		p.cmd.Dir = dir
+// get the environment of the program
+func (p *Process) getEnv() ([]string, error) {
+	fileEnv, err := p.config.GetEnvFiles("env_file")
+	if err != nil {
+		return nil, errors.As(err, p.GetName())
 	}
+	// the secrets are only resolved into the environment of the child process.
+	env, err := config.ResolveSecretEnv(append(fileEnv, p.config.GetEnv("environment")...))
+	if err != nil {
+		return nil, errors.As(err, p.GetName())
+	}
+	// the port allocated by port_range, the environment can override it
+	if port := p.config.GetString("port", ""); port != "" {
+		env = append([]string{"PORT=" + port}, env...)
+	}
+	return append(os.Environ(), env...), nil
+}
+
+func (p *Process) setDir() {
+	p.cmd.Dir = p.getDir()
+}
+
+func (p *Process) getDir() string {
+	return p.config.GetStringExpression("directory", "")
 }
 
 func (p *Process) setLog() error {
 	if p.config.IsProgram() {
-		var err error
-		p.StdoutLog, err = p.createLogger(
-			p.GetStdoutLogfile(),
-			int64(p.config.GetBytes("stdout_logfile_maxbytes", 50*1024*1024)),
-			p.config.GetInt("stdout_logfile_backups", 10),
-			p.createStdoutLogEventEmitter(),
-		)
-		if err != nil {
-			return err
-		}
-		capture_bytes := p.config.GetBytes("stdout_capture_maxbytes", 0)
-		if capture_bytes > 0 {
-			log.WithFields(log.Fields{"program": p.config.GetProgramName()}).Info("capture stdout process communication")
-			p.StdoutLog = logger.NewLogCaptureLogger(p.StdoutLog,
-				capture_bytes,
-				"PROCESS_COMMUNICATION_STDOUT",
-				p.GetName(),
-				p.GetGroup())
+		if err := p.createLoggers(); err != nil {
+			return errors.As(err)
 		}
-
-		p.cmd.Stdout = p.StdoutLog
-
-		if p.config.GetBool("redirect_stderr", false) {
-			p.StderrLog = p.StdoutLog
-		} else {
-			p.StderrLog, err = p.createLogger(
-				p.GetStderrLogfile(),
-				int64(p.config.GetBytes("stderr_logfile_maxbytes", 50*1024*1024)),
-				p.config.GetInt("stderr_logfile_backups", 10),
-				p.createStderrLogEventEmitter(),
-			)
-			if err != nil {
-				return errors.As(err)
+		if p.isAdoptable() {
+			err := p.setAdoptableLog()
+			if err == nil {
+				return nil
+			}
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to create the log fifo, the log can't be reattached", errors.As(err))
+			p.closeLogFifos()
+			for _, f := range p.closeAfterStart {
+				f.Close()
 			}
+			p.closeAfterStart = nil
 		}
-
-		capture_bytes = p.config.GetBytes("stderr_capture_maxbytes", 0)
-
-		if capture_bytes > 0 {
-			log.WithFields(log.Fields{"program": p.config.GetProgramName()}).Info("capture stderr process communication")
-			p.StderrLog = logger.NewLogCaptureLogger(p.StdoutLog,
-				capture_bytes,
-				"PROCESS_COMMUNICATION_STDERR",
-				p.GetName(),
-				p.GetGroup())
+		if p.isDaemonizes() {
+			return p.setDaemonLog()
 		}
-
+		p.cmd.Stdout = p.StdoutLog
 		p.cmd.Stderr = p.StderrLog
-
 	} else if p.config.IsEventListener() {
 		in, err := p.cmd.StdoutPipe()
 		if err != nil {
@@ -698,6 +1000,57 @@ func (p *Process) setLog() error {
 	return nil
 }
 
+// create the stdout and stderr loggers of the program
+func (p *Process) createLoggers() error {
+	var err error
+	p.StdoutLog, err = p.createLogger(
+		p.GetStdoutLogfile(),
+		int64(p.config.GetBytes("stdout_logfile_maxbytes", 50*1024*1024)),
+		p.config.GetInt("stdout_logfile_backups", 10),
+		p.createStdoutLogEventEmitter(),
+	)
+	if err != nil {
+		return err
+	}
+	capture_bytes := p.config.GetBytes("stdout_capture_maxbytes", 0)
+	if capture_bytes > 0 {
+		log.WithFields(log.Fields{"program": p.config.GetProgramName()}).Info("capture stdout process communication")
+		p.StdoutLog = logger.NewLogCaptureLogger(p.StdoutLog,
+			capture_bytes,
+			"PROCESS_COMMUNICATION_STDOUT",
+			p.GetName(),
+			p.GetGroup())
+	}
+
+	if p.config.GetBool("redirect_stderr", false) {
+		p.StderrLog = p.StdoutLog
+	} else {
+		p.StderrLog, err = p.createLogger(
+			p.GetStderrLogfile(),
+			int64(p.config.GetBytes("stderr_logfile_maxbytes", 50*1024*1024)),
+			p.config.GetInt("stderr_logfile_backups", 10),
+			p.createStderrLogEventEmitter(),
+		)
+		if err != nil {
+			return errors.As(err)
+		}
+	}
+
+	capture_bytes = p.config.GetBytes("stderr_capture_maxbytes", 0)
+
+	if capture_bytes > 0 {
+		log.WithFields(log.Fields{"program": p.config.GetProgramName()}).Info("capture stderr process communication")
+		p.StderrLog = logger.NewLogCaptureLogger(p.StdoutLog,
+			capture_bytes,
+			"PROCESS_COMMUNICATION_STDERR",
+			p.GetName(),
+			p.GetGroup())
+	}
+	p.setOutputRules()
+	p.setOutputActivity()
+	return nil
+}
+
 func (p *Process) createStdoutLogEventEmitter() logger.LogEventEmitter {
 	if p.config.GetBytes("stdout_capture_maxbytes", 0) <= 0 && p.config.GetBool("stdout_events_enabled", false) {
 		return logger.NewStdoutLogEventEmitter(p.config.GetProgramName(), p.config.GetGroupName(), func() int {
@@ -737,9 +1090,24 @@ func (p *Process) createLogger(logFile string, maxBytes int64, backups int, logE
 }
 
 func (p *Process) setUser() error {
+	return p.setCmdUser(p.cmd)
+}
+
+// set the user of the command by the user of the program
+func (p *Process) setCmdUser(cmd *exec.Cmd) error {
+	uid, gid, ok, err := p.lookupUser()
+	if err != nil || !ok {
+		return err
+	}
+	set_user_id(cmd.SysProcAttr, uid, gid)
+	return nil
+}
+
+// get the uid and gid of the user of the program, ok is false if the user is not set.
+func (p *Process) lookupUser() (uid uint32, gid uint32, ok bool, err error) {
 	userName := p.config.GetString("user", "")
 	if len(userName) == 0 {
-		return nil
+		return 0, 0, false, nil
 	}
 
 	//check if group is provided
@@ -751,35 +1119,67 @@ func (p *Process) setUser() error {
 	}
 	u, err := user.Lookup(userName)
 	if err != nil {
-		return err
+		return 0, 0, false, err
 	}
-	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	userId, err := strconv.ParseUint(u.Uid, 10, 32)
 	if err != nil {
-		return err
+		return 0, 0, false, err
 	}
-	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	groupId, err := strconv.ParseUint(u.Gid, 10, 32)
 	if err != nil && groupName == "" {
-		return err
+		return 0, 0, false, err
 	}
 	if groupName != "" {
 		g, err := user.LookupGroup(groupName)
 		if err != nil {
-			return err
+			return 0, 0, false, err
 		}
-		gid, err = strconv.ParseUint(g.Gid, 10, 32)
+		groupId, err = strconv.ParseUint(g.Gid, 10, 32)
 		if err != nil {
-			return err
+			return 0, 0, false, err
 		}
 	}
-	set_user_id(p.cmd.SysProcAttr, uint32(uid), uint32(gid))
-	return nil
+	return uint32(userId), uint32(groupId), true, nil
+}
+
+// Restart stop and start the program, e.g. by the watchdog or ctl restart, call without lock.
+//
+// The start waits the start loop of the stopped program exits, or it is refused as already started.
+// Args:
+//  wait - true, wait the program started or failed
+func (p *Process) Restart(wait bool) {
+	p.Stop(true)
+	// the start loop may sleep 5 seconds if the program exits too quickly
+	for i := 0; i < 100; i++ {
+		p.lock.RLock()
+		inStart := p.inStart
+		p.lock.RUnlock()
+		if !inStart {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	p.Start(wait)
 }
 
 //send signal to process to stop it
 func (p *Process) Stop(wait bool) {
 	p.lock.Lock()
+	// the detached child is stopped by the next supervisor
+	if p.detached {
+		p.lock.Unlock()
+		return
+	}
 	p.stopByUser = true
+	p.cancelStandby()
 	p.lock.Unlock()
+	p.stopWatch()
+	// the paused program can't handle the stop signals
+	if p.GetState() == PAUSED {
+		if err := p.Resume(); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to resume the paused program:", err)
+		}
+	}
 	log.WithFields(log.Fields{"program": p.GetName()}).Info("stop the program")
 	sigs := strings.Fields(p.config.GetString("stopsignal", ""))
 	waitsecs := time.Duration(p.config.GetInt("stopwaitsecs", 10)) * time.Second
@@ -789,12 +1189,24 @@ func (p *Process) Stop(wait bool) {
 		log.WithFields(log.Fields{"program": p.GetName()}).Error("Cannot set stopasgroup=true and killasgroup=false")
 	}
 
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
+		running := !p.Stoped()
+		if running {
+			if err := p.runHook(HOOK_PRE_STOP); err != nil {
+				log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to run the pre_stop hook, stop the program anyway:", err)
+			}
+		}
 		stopped := false
+		if running && p.config.GetString("stop_command", "") != "" {
+			stopped = p.runStopCommand(waitsecs)
+		}
 		for i := 0; i < len(sigs) && !stopped; i++ {
 			// send signal to process
 			sig, err := signals.ToSignal(sigs[i])
 			if err != nil {
+				log.WithFields(log.Fields{"program": p.GetName(), "signal": sigs[i]}).Warn("skip the unsupported stop signal:", err)
 				continue
 			}
 			log.WithFields(log.Fields{"program": p.GetName(), "signal": sigs[i]}).Info("send stop signal to program")
@@ -803,7 +1215,7 @@ func (p *Process) Stop(wait bool) {
 			//wait at most "stopwaitsecs" seconds for one signal
 			for endTime.After(time.Now()) {
 				//if it already exits
-				if p.state != STARTING && p.state != RUNNING && p.state != STOPPING {
+				if p.Stoped() {
 					stopped = true
 					break
 				}
@@ -814,21 +1226,53 @@ func (p *Process) Stop(wait bool) {
 			log.WithFields(log.Fields{"program": p.GetName()}).Info("force to kill the program")
 			p.Signal(syscall.SIGKILL, killasgroup)
 		}
+		if running && p.hasHook(HOOK_POST_STOP) {
+			// the killed program may hang in the kernel, wait at most stopwaitsecs more
+			endTime := time.Now().Add(waitsecs)
+			for !p.Stoped() && endTime.After(time.Now()) {
+				time.Sleep(100 * time.Millisecond)
+			}
+			if !p.Stoped() {
+				log.WithFields(log.Fields{"program": p.GetName()}).Warn("the program does not exit after killed, skip the post_stop hook")
+			} else if err := p.runHook(HOOK_POST_STOP); err != nil {
+				log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to run the post_stop hook:", err)
+			}
+		}
 	}()
 	if wait {
 		for {
 			// if the program exits
 			p.lock.RLock()
-			if p.state != STARTING && p.state != RUNNING && p.state != STOPPING {
+			if p.state != STARTING && p.state != RUNNING && p.state != STOPPING && p.state != PAUSED {
 				p.lock.RUnlock()
 				break
 			}
 			p.lock.RUnlock()
 			time.Sleep(1 * time.Second)
 		}
+		<-done
 	}
 }
 
+// stop the program by the stop_command, return true if the program exits in waitsecs.
+//
+// The pid of the program is passed to the command by the environment SUPD_PID.
+func (p *Process) runStopCommand(waitsecs time.Duration) bool {
+	endTime := time.Now().Add(waitsecs)
+	if err := p.runCommand("stop_command", waitsecs, fmt.Sprintf("SUPD_PID=%d", p.GetPid())); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to stop the program by the stop_command, send the stop signal:", err)
+		return p.Stoped()
+	}
+	for endTime.After(time.Now()) {
+		if p.Stoped() {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("the program is still running after the stop_command, send the stop signal")
+	return p.Stoped()
+}
+
 func (p *Process) GetStatus() string {
 	if p.cmd.ProcessState.Exited() {
 		return p.cmd.ProcessState.String()
@@ -839,8 +1283,35 @@ func (p *Process) GetStatus() string {
 func (p *Process) Stoped() bool {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
-	return p.state != STARTING && p.state != RUNNING && p.state != STOPPING
+	return p.state != STARTING && p.state != RUNNING && p.state != STOPPING && p.state != PAUSED
 }
 func (p *Process) StopedByUser() bool {
 	return p.stopByUser && p.Stoped()
 }
+
+// get the reason why the last spawn failed, empty if the program is spawned.
+func (p *Process) GetSpawnErr() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.spawnErr
+}
+
+// get the reason why the program is skipped by its conditions, empty if it is not skipped.
+func (p *Process) GetSkipReason() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.skipReason
+}
+
+// disable or enable the program, the disabled program can't be started until it is enabled.
+func (p *Process) SetDisabled(disabled bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.disabled = disabled
+}
+
+func (p *Process) IsDisabled() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.disabled
+}
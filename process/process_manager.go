@@ -13,6 +13,11 @@ type ProcessManager struct {
 	procs          map[string]*Process
 	eventListeners map[string]*Process
 	lock           sync.Mutex
+
+	// the directory of the log fifos of the adoptable programs
+	adoptDir string
+	// the directory of the notify sockets of the programs
+	notifyDir string
 }
 
 func NewProcessManager() *ProcessManager {
@@ -38,12 +43,30 @@ func (pm *ProcessManager) createProgram(supervisor_id string, config *config.Con
 	proc, ok := pm.procs[procName]
 	if !ok {
 		proc = NewProcess(supervisor_id, config)
+		proc.adoptDir = pm.adoptDir
+		proc.notifyDir = pm.notifyDir
+		proc.procMgr = pm
 		pm.procs[procName] = proc
 		log.Info("create process:", procName)
 	}
 	return proc
 }
 
+// set the directory of the log fifos, the programs created later are adoptable
+// by the next supd if the directory is not empty.
+func (pm *ProcessManager) SetAdoptDir(dir string) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.adoptDir = dir
+}
+
+// set the directory of the notify sockets, the programs created later can speak the sd_notify protocol.
+func (pm *ProcessManager) SetNotifyDir(dir string) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.notifyDir = dir
+}
+
 func (pm *ProcessManager) createEventListener(supervisor_id string, config *config.ConfigEntry) *Process {
 	eventListenerName := config.GetEventListenerName()
 
@@ -116,6 +139,18 @@ func (pm *ProcessManager) FindMatch(name string) []*Process {
 	return result
 }
 
+// find the program by the pid of its running process, nil if it is not found.
+func (pm *ProcessManager) FindByPid(pid int) *Process {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	for _, proc := range pm.procs {
+		if proc.GetPid() == pid {
+			return proc
+		}
+	}
+	return nil
+}
+
 // clear all the processes
 func (pm *ProcessManager) Clear() {
 	pm.lock.Lock()
@@ -0,0 +1,33 @@
+package process
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gwaycc/supd/config"
+)
+
+// create the process of the program in the section, the section starts with [program:<name>]
+// and it is written to the supd.ini in the dir, so %(here)s is the dir.
+func newTestProcess(t *testing.T, dir string, section string) *Process {
+	header := strings.SplitN(section, "\n", 2)[0]
+	if !strings.HasPrefix(header, "[program:") || !strings.HasSuffix(header, "]") {
+		t.Fatalf("the section is not a program: %s", header)
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(header, "[program:"), "]")
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(section), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf := config.NewConfig(confFile)
+	if _, err := conf.Load(); err != nil {
+		t.Fatal(err)
+	}
+	entry := conf.GetProgram(name)
+	if entry == nil {
+		t.Fatalf("the program %s is not loaded", name)
+	}
+	return NewProcess("supervisord", entry)
+}
@@ -0,0 +1,128 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gwaycc/supd/signals"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the keys which are only used by supd, the running program is not affected when they are changed.
+var supervisorKeys = map[string]bool{
+	"autostart":              true,
+	"autorestart":            true,
+	"startsecs":              true,
+	"startretries":           true,
+	"restartpause":           true,
+	"exitcodes":              true,
+	"priority":               true,
+	"stopsignal":             true,
+	"stopwaitsecs":           true,
+	"stopasgroup":            true,
+	"killasgroup":            true,
+	"stop_command":           true,
+	"reload_signal":          true,
+	"reload_command":         true,
+	"reload_command_timeout": true,
+	"reload_keys":            true,
+	"watch_paths":            true,
+	"watch_action":           true,
+	"watch_delay":            true,
+	"output_timeout_action":  true,
+	"labels":                 true,
+	"singleton_lock_refresh": true,
+	// the conditions are checked only before the start
+	"condition_path_exists":     true,
+	"condition_env":             true,
+	"condition_host":            true,
+	"condition_command":         true,
+	"condition_command_timeout": true,
+}
+
+func init() {
+	for _, hook := range []string{HOOK_PRE_START, HOOK_POST_START, HOOK_PRE_STOP, HOOK_POST_STOP} {
+		supervisorKeys[hook] = true
+		supervisorKeys[hook+"_timeout"] = true
+	}
+}
+
+// filter the changed keys which affect the running program.
+func ProgramKeys(keys []string) []string {
+	result := []string{}
+	for _, key := range keys {
+		if !supervisorKeys[key] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// true if the program can reload its configuration in place
+func (p *Process) CanReload() bool {
+	return p.config.GetString("reload_command", "") != "" || p.config.GetString("reload_signal", "") != ""
+}
+
+// true if the changed keys can be applied by reloading the program in place,
+// the keys are whitelisted by reload_keys.
+func (p *Process) CanReloadKeys(keys []string) bool {
+	if !p.CanReload() {
+		return false
+	}
+	reloadKeys := map[string]bool{}
+	for _, key := range strings.FieldsFunc(p.config.GetString("reload_keys", ""), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		reloadKeys[key] = true
+	}
+	for _, key := range keys {
+		if !reloadKeys[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// ask the running program to reload its configuration without a restart.
+//
+// The reload_command is run if it is configured, or the reload_signal is sent to the program.
+func (p *Process) Reload() error {
+	pid := p.GetPid()
+	if pid == 0 {
+		return errors.New("the program is not running").As(p.GetName())
+	}
+	if p.config.GetString("reload_command", "") != "" {
+		timeout := time.Duration(p.config.GetInt("reload_command_timeout", 30)) * time.Second
+		if err := p.runCommand("reload_command", timeout, fmt.Sprintf("SUPD_PID=%d", pid)); err != nil {
+			return errors.As(err, p.GetName())
+		}
+		log.WithFields(log.Fields{"program": p.GetName()}).Info("success to reload the program by the reload_command")
+		return nil
+	}
+
+	sigName := p.config.GetString("reload_signal", "")
+	if sigName == "" {
+		return errors.New("reload_signal or reload_command is not configured").As(p.GetName())
+	}
+	sig, err := toSignal(sigName)
+	if err != nil {
+		return errors.As(err, p.GetName())
+	}
+	log.WithFields(log.Fields{"program": p.GetName(), "signal": sigName}).Info("send reload signal to program")
+	if err := p.Signal(sig, p.config.GetBool("stopasgroup", false)); err != nil {
+		return errors.As(err, p.GetName(), sigName)
+	}
+	return nil
+}
+
+// convert the signal name to the signal, the unsupported name is rejected.
+func toSignal(sigName string) (os.Signal, error) {
+	sig, err := signals.ToSignal(sigName)
+	if err != nil {
+		return nil, errors.As(err, sigName)
+	}
+	return sig, nil
+}
@@ -0,0 +1,60 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestCanReloadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:reload]
+command=/bin/cat
+reload_signal=HUP
+reload_keys=environment, env_file
+`)
+	keys := ProgramKeys([]string{"autorestart", "environment", "pre_start_timeout"})
+	if len(keys) != 1 || keys[0] != "environment" {
+		t.Errorf("Fail to filter the program keys: %v", keys)
+	}
+	if !proc.CanReloadKeys(keys) {
+		t.Error("expect the environment can be reloaded")
+	}
+	if proc.CanReloadKeys([]string{"environment", "command"}) {
+		t.Error("expect the command can't be reloaded")
+	}
+	if err := proc.Reload(); err == nil {
+		t.Error("expect the program is not running")
+	}
+}
+
+func TestToSignal(t *testing.T) {
+	for name, expect := range map[string]os.Signal{
+		"TERM":    syscall.SIGTERM,
+		"SIGTERM": syscall.SIGTERM,
+		"SIGHUP":  syscall.SIGHUP,
+		"hup":     syscall.SIGHUP,
+		"usr1":    syscall.SIGUSR1,
+	} {
+		sig, err := toSignal(name)
+		if err != nil {
+			t.Errorf("Fail to convert the signal %s: %v", name, err)
+			continue
+		}
+		if sig != expect {
+			t.Errorf("expect %s is %v, but it is %v", name, expect, sig)
+		}
+	}
+	for _, name := range []string{"", "HUPP", "SIGFOO"} {
+		if sig, err := toSignal(name); err == nil {
+			t.Errorf("expect %q is unsupported, but it is %v", name, sig)
+		}
+	}
+}
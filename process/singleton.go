@@ -0,0 +1,197 @@
+package process
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// SingletonLock is the lock shared by the supd hosts, the program runs only on the host holding it.
+type SingletonLock interface {
+	// TryLock acquire the lock without blocking, false if the lock is held by the other
+	TryLock() (bool, error)
+	// Refresh keep the lock alive while it is held, the error means the lock is lost
+	Refresh() error
+	// Unlock release the lock
+	Unlock() error
+}
+
+// SingletonLockBackend create the lock of the target, e.g. the path of the file backend
+type SingletonLockBackend func(target string, entry *config.ConfigEntry) (SingletonLock, error)
+
+var (
+	singletonBackendLock sync.RWMutex
+	singletonBackends    = map[string]SingletonLockBackend{
+		"file": newFileLock,
+	}
+)
+
+// RegisterSingletonLockBackend register the backend of singleton_lock=<scheme>://<target>
+func RegisterSingletonLockBackend(scheme string, backend SingletonLockBackend) {
+	singletonBackendLock.Lock()
+	defer singletonBackendLock.Unlock()
+	singletonBackends[scheme] = backend
+}
+
+// create the lock by the backend of the scheme, the path without scheme is for the file backend.
+//
+//  singleton_lock=/shared/locks/foo.lock
+//  singleton_lock=file:///shared/locks/foo.lock
+func newSingletonLock(value string, entry *config.ConfigEntry) (SingletonLock, error) {
+	scheme, target := "file", value
+	if pos := strings.Index(value, "://"); pos > 0 {
+		scheme, target = value[:pos], value[pos+3:]
+	}
+	singletonBackendLock.RLock()
+	backend, ok := singletonBackends[scheme]
+	singletonBackendLock.RUnlock()
+	if !ok {
+		return nil, errors.New("unsupported backend of singleton_lock").As(value)
+	}
+	return backend(target, entry)
+}
+
+func (p *Process) getSingletonLock() string {
+	return p.config.GetStringExpression("singleton_lock", "")
+}
+
+// the interval to refresh the held lock and to retry the lock in STANDBY
+func (p *Process) getSingletonRefresh() time.Duration {
+	return time.Duration(p.config.GetInt("singleton_lock_refresh", 5)) * time.Second
+}
+
+// acquire the singleton lock, true if the program can be started.
+func (p *Process) tryAcquireSingleton() bool {
+	value := p.getSingletonLock()
+	if value == "" {
+		return true
+	}
+	p.lock.RLock()
+	held := p.singleton != nil
+	p.lock.RUnlock()
+	if held {
+		return true
+	}
+	// the shared filesystem may be slow, lock it without the lock of process
+	lock, err := newSingletonLock(value, p.config)
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to create the singleton lock:", err)
+		return false
+	}
+	locked, err := lock.TryLock()
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to acquire the singleton lock:", err)
+		return false
+	}
+	if !locked {
+		return false
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.singleton != nil {
+		lock.Unlock()
+		return true
+	}
+	log.WithFields(log.Fields{"program": p.GetName(), "lock": value}).Info("the singleton lock is acquired")
+	p.singleton = lock
+	go p.refreshSingleton(lock)
+	return true
+}
+
+// keep the held lock alive, the program goes back to STANDBY if the lock is lost.
+func (p *Process) refreshSingleton(lock SingletonLock) {
+	for {
+		time.Sleep(p.getSingletonRefresh())
+		p.lock.RLock()
+		held := p.singleton == lock && !p.stopByUser
+		p.lock.RUnlock()
+		if !held {
+			return
+		}
+		if err := lock.Refresh(); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("the singleton lock is lost, stop the program:", err)
+			p.Restart(false)
+			return
+		}
+	}
+}
+
+// release the held singleton lock, so the standby on the other host can take over.
+func (p *Process) releaseSingleton() {
+	p.lock.Lock()
+	lock := p.singleton
+	p.singleton = nil
+	p.lock.Unlock()
+	if lock == nil {
+		return
+	}
+	if err := lock.Unlock(); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to release the singleton lock:", err)
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName()}).Info("the singleton lock is released")
+}
+
+// kill the child left by the previous supd, it can't run without the singleton lock.
+func (p *Process) killAdopting() {
+	p.lock.Lock()
+	child := p.adopting
+	p.adopting = nil
+	p.lock.Unlock()
+	if child == nil {
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName(), "pid": child.Pid}).Warn("the singleton lock is held by the other, kill the left child")
+	if proc, err := os.FindProcess(child.Pid); err == nil {
+		proc.Kill()
+	}
+}
+
+// enter STANDBY, the returned channel is closed if the program is stopped.
+func (p *Process) enterStandby() chan struct{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	cancel := make(chan struct{})
+	p.standbyCancel = cancel
+	p.changeStateTo(STANDBY)
+	log.WithFields(log.Fields{"program": p.GetName(), "lock": p.getSingletonLock()}).Info("the singleton lock is held by the other, the program is in STANDBY")
+	return cancel
+}
+
+// wait in STANDBY until the singleton lock is acquired, false if the program is stopped.
+func (p *Process) waitSingleton(cancel chan struct{}) bool {
+	defer func() {
+		p.lock.Lock()
+		if p.standbyCancel == cancel {
+			p.standbyCancel = nil
+		}
+		p.lock.Unlock()
+	}()
+	for {
+		select {
+		case <-cancel:
+			return false
+		case <-time.After(p.getSingletonRefresh()):
+		}
+		if p.tryAcquireSingleton() {
+			return true
+		}
+	}
+}
+
+// leave STANDBY for the stop, call with lock.
+func (p *Process) cancelStandby() {
+	if p.standbyCancel == nil {
+		return
+	}
+	close(p.standbyCancel)
+	p.standbyCancel = nil
+	if p.state == STANDBY {
+		p.changeStateTo(STOPPED)
+	}
+}
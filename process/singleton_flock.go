@@ -0,0 +1,81 @@
+// +build !windows
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaylib/errors"
+)
+
+// the flock on the file of the shared filesystem. The lock is released by the kernel when the holder
+// exits, or by the NFS server when the lease of the dead host expires, so the file is never removed.
+type fileLock struct {
+	path string
+	file *os.File
+}
+
+func newFileLock(path string, entry *config.ConfigEntry) (SingletonLock, error) {
+	if path == "" {
+		return nil, errors.New("the path of singleton_lock is empty")
+	}
+	return &fileLock{path: path}, nil
+}
+
+func (l *fileLock) TryLock() (bool, error) {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, errors.As(err, l.path)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err != syscall.EWOULDBLOCK {
+			return false, errors.As(err, l.path)
+		}
+		return false, nil
+	}
+	// the file may be removed by hand before it is locked
+	if !l.isCurrent(file) {
+		file.Close()
+		return false, nil
+	}
+	host, _ := os.Hostname()
+	file.Truncate(0)
+	file.WriteAt([]byte(fmt.Sprintf("%s %d\n", host, os.Getpid())), 0)
+	l.file = file
+	return true, nil
+}
+
+// true if the path is the locked file
+func (l *fileLock) isCurrent(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(l.path)
+	return err == nil && os.SameFile(info, current)
+}
+
+func (l *fileLock) Refresh() error {
+	if l.file == nil {
+		return errors.New("the singleton lock is not held").As(l.path)
+	}
+	// the other can lock the new file if the locked one is removed
+	if !l.isCurrent(l.file) {
+		return errors.New("the file of singleton lock is removed or replaced").As(l.path)
+	}
+	return nil
+}
+
+func (l *fileLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	file := l.file
+	l.file = nil
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return file.Close()
+}
@@ -0,0 +1,128 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gwaycc/supd/config"
+)
+
+// wait the state of the program in seconds
+func waitState(proc *Process, state ProcessState, seconds int) bool {
+	for i := 0; i < seconds*10; i++ {
+		if proc.GetState() == state {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func TestSingletonLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-singleton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	program := `[program:singleton]
+command=/bin/sleep 30
+startsecs=0
+singleton_lock=%(here)s/singleton.lock
+singleton_lock_refresh=1
+`
+	// the programs of two hosts share the lock
+	first := newTestProcess(t, dir, program)
+	second := newTestProcess(t, dir, program)
+
+	first.Start(true)
+	defer first.Stop(true)
+	if first.GetState() != RUNNING {
+		t.Fatalf("expect the holder is RUNNING, but it is %s", first.GetState())
+	}
+	second.Start(true)
+	defer second.Stop(true)
+	if second.GetState() != STANDBY || !strings.Contains(second.GetDescription(), "singleton lock") {
+		t.Fatalf("expect the other is in STANDBY, but it is %s: %s", second.GetState(), second.GetDescription())
+	}
+
+	// the standby takes over after the lock is released
+	first.Stop(true)
+	if !waitState(second, RUNNING, 5) {
+		t.Fatalf("expect the standby takes over, but it is %s", second.GetState())
+	}
+
+	// the stopped standby does not take over
+	first.Start(true)
+	if first.GetState() != STANDBY {
+		t.Fatalf("expect STANDBY, but it is %s", first.GetState())
+	}
+	first.Stop(true)
+	if first.GetState() != STOPPED {
+		t.Fatalf("expect the standby is stopped, but it is %s", first.GetState())
+	}
+	second.Stop(true)
+	time.Sleep(2 * time.Second)
+	if first.GetState() != STOPPED {
+		t.Fatalf("expect the stopped standby is not started, but it is %s", first.GetState())
+	}
+}
+
+func TestFileLockRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-singleton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "release.lock")
+	entry := config.NewConfigEntry(dir)
+
+	holder, _ := newFileLock(path, entry)
+	if locked, err := holder.TryLock(); err != nil || !locked {
+		t.Fatal("expect the lock is acquired", err)
+	}
+	other, _ := newFileLock(path, entry)
+	// the old file of the live holder is never taken over
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if locked, err := other.TryLock(); err != nil || locked {
+			t.Fatal("expect the lock is held", err)
+		}
+	}
+	if err := holder.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	holder.Unlock()
+	if locked, err := other.TryLock(); err != nil || !locked {
+		t.Fatal("expect the released lock is taken over", err)
+	}
+	defer other.Unlock()
+	if err := other.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSingletonNotAdoptable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-singleton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:singleton]
+command=/bin/sleep 30
+singleton_lock=%(here)s/singleton.lock
+`)
+	proc.adoptDir = dir
+	if proc.isAdoptable() {
+		t.Fatal("expect the program with singleton_lock is not adoptable")
+	}
+}
@@ -0,0 +1,11 @@
+package process
+
+import (
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaylib/errors"
+)
+
+// the flock is not supported on windows, a backend can be registered for the other scheme
+func newFileLock(path string, entry *config.ConfigEntry) (SingletonLock, error) {
+	return nil, errors.New("the file backend of singleton_lock is not supported on windows").As(path)
+}
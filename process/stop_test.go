@@ -0,0 +1,119 @@
+// +build !windows
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// start the program which records the TERM in term.log and exits, the stop_command records SUPD_PID in pid.log.
+func startStopTestProcess(t *testing.T, dir string, stopScript string, stopwaitsecs int) *Process {
+	program := "trap 'echo term > " + filepath.Join(dir, "term.log") + "; exit 0' TERM\nwhile true; do sleep 0.1; done\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "program.sh"), []byte(program), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stopScript = "echo $SUPD_PID > " + filepath.Join(dir, "pid.log") + "\n" + stopScript
+	if err := ioutil.WriteFile(filepath.Join(dir, "stop.sh"), []byte(stopScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	proc := newTestProcess(t, dir, fmt.Sprintf(`[program:stop]
+command=/bin/sh %%(here)s/program.sh
+startsecs=0
+stop_command=/bin/sh %%(here)s/stop.sh
+stopsignal=TERM
+stopwaitsecs=%d
+`, stopwaitsecs))
+	proc.Start(true)
+	if proc.GetState() != RUNNING {
+		t.Fatalf("expect the program is running, but it is %s", proc.GetState())
+	}
+	// the trap is set after the shell starts
+	time.Sleep(200 * time.Millisecond)
+	return proc
+}
+
+// check the stop_command gets the pid of the program and if the stop signal is sent
+func checkStopCommand(t *testing.T, dir string, pid int, expectTerm bool) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "pid.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != fmt.Sprint(pid) {
+		t.Errorf("expect SUPD_PID is %d, but it is %s", pid, data)
+	}
+	_, err = os.Stat(filepath.Join(dir, "term.log"))
+	if expectTerm && err != nil {
+		t.Error("expect the stop signal is sent")
+	} else if !expectTerm && err == nil {
+		t.Error("expect the stop signal is not sent")
+	}
+}
+
+func TestStopCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := startStopTestProcess(t, dir, "kill -KILL $SUPD_PID\n", 5)
+	pid := proc.GetPid()
+
+	start := time.Now()
+	proc.Stop(true)
+	if !proc.Stoped() {
+		t.Fatalf("expect the program is stopped, but it is %s", proc.GetState())
+	}
+	if time.Since(start) >= 5*time.Second {
+		t.Errorf("expect the program is stopped by the command, but it takes %v", time.Since(start))
+	}
+	checkStopCommand(t, dir, pid, false)
+}
+
+func TestStopCommandSurvived(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := startStopTestProcess(t, dir, "exit 0\n", 1)
+	pid := proc.GetPid()
+
+	start := time.Now()
+	proc.Stop(true)
+	if !proc.Stoped() {
+		t.Fatalf("expect the program is stopped, but it is %s", proc.GetState())
+	}
+	if time.Since(start) < time.Second {
+		t.Errorf("expect the stop signal is sent after stopwaitsecs, but it takes %v", time.Since(start))
+	}
+	checkStopCommand(t, dir, pid, true)
+}
+
+func TestStopCommandFailed(t *testing.T) {
+	for name, script := range map[string]string{
+		"failed":  "exit 1\n",
+		"timeout": "sleep 30\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "supd-stop")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			proc := startStopTestProcess(t, dir, script, 1)
+			pid := proc.GetPid()
+
+			proc.Stop(true)
+			if !proc.Stoped() {
+				t.Fatalf("expect the program is stopped, but it is %s", proc.GetState())
+			}
+			checkStopCommand(t, dir, pid, true)
+		})
+	}
+}
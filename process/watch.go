@@ -0,0 +1,228 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/fsnotify.v1"
+)
+
+// the watch of the program files, the watch_action is applied to the program
+// when its executable or the files matched by watch_paths are changed.
+type fileWatch struct {
+	watcher  *fsnotify.Watcher
+	patterns []string
+
+	lock   sync.Mutex
+	timer  *time.Timer
+	closed bool
+}
+
+// true if the file matches one of the watched patterns
+func (w *fileWatch) match(name string) bool {
+	for _, pattern := range w.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply the action after the delay, the timer is reset by the later changes.
+func (w *fileWatch) debounce(delay time.Duration, action func()) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(delay, action)
+}
+
+func (w *fileWatch) close() {
+	w.lock.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.lock.Unlock()
+	w.watcher.Close()
+}
+
+// true if the files of the program are watched
+func (p *Process) isWatched() bool {
+	return p.config.IsProgram() && (p.config.GetString("watch_paths", "") != "" || p.config.GetString("watch_action", "") != "")
+}
+
+// get the patterns of the watched files, they are the executable of the program and the watch_paths.
+//
+// The relative paths are relative to the directory of the program.
+func (p *Process) getWatchPatterns() ([]string, error) {
+	args, err := parseCommand(p.config.GetStringExpression("command", ""))
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	exe := args[0]
+	if !strings.ContainsRune(exe, os.PathSeparator) {
+		if exe, err = exec.LookPath(exe); err != nil {
+			return nil, errors.As(err, args[0])
+		}
+	}
+	patterns := append([]string{exe}, strings.FieldsFunc(p.config.GetStringExpression("watch_paths", ""), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})...)
+
+	dir := p.getDir()
+	if dir == "" {
+		dir = p.config.ConfigDir
+	}
+	for i, pattern := range patterns {
+		pattern, err := Path_expand(pattern)
+		if err != nil {
+			return nil, errors.As(err, patterns[i])
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.As(err, patterns[i])
+		}
+		patterns[i] = filepath.Clean(pattern)
+	}
+	return patterns, nil
+}
+
+// get the action applied to the program when the watched files are changed.
+//
+//  watch_action=restart|reload|signal:HUP
+func (p *Process) getWatchAction() (action string, sig os.Signal, err error) {
+	action = p.config.GetString("watch_action", "restart")
+	switch {
+	case action == "restart" || action == "reload":
+		return action, nil, nil
+	case strings.HasPrefix(action, "signal:"):
+		sig, err := toSignal(strings.TrimPrefix(action, "signal:"))
+		if err != nil {
+			return "", nil, errors.As(err, action)
+		}
+		return action, sig, nil
+	}
+	return "", nil, errors.New("unsupported watch_action").As(action)
+}
+
+// start to watch the files of the program, the previous watch is replaced. call without lock.
+func (p *Process) startWatch() {
+	p.stopWatch()
+	if !p.isWatched() {
+		return
+	}
+	if _, _, err := p.getWatchAction(); err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error("fail to watch the files of program:", err)
+		return
+	}
+	patterns, err := p.getWatchPatterns()
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error("fail to watch the files of program:", err)
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error("fail to watch the files of program:", errors.As(err))
+		return
+	}
+	// watch the directories because the files may be replaced by renaming, e.g. rsync.
+	dirs := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Dir(pattern))
+		if len(matches) == 0 {
+			log.WithFields(log.Fields{"program": p.GetName(), "path": pattern}).Warn("the directory of the watched path is not found")
+		}
+		for _, dir := range matches {
+			if dirs[dir] {
+				continue
+			}
+			dirs[dir] = true
+			if err := watcher.Add(dir); err != nil {
+				log.WithFields(log.Fields{"program": p.GetName(), "path": dir}).Warn("fail to watch the directory:", err)
+			}
+		}
+	}
+
+	w := &fileWatch{watcher: watcher, patterns: patterns}
+	p.lock.Lock()
+	p.watch = w
+	p.lock.Unlock()
+	log.WithFields(log.Fields{"program": p.GetName(), "paths": patterns}).Info("watch the files of program")
+
+	go func() {
+		delay := time.Duration(p.config.GetInt("watch_delay", 1)) * time.Second
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create) == 0 || !w.match(event.Name) {
+					continue
+				}
+				name := event.Name
+				w.debounce(delay, func() {
+					p.applyWatchAction(w, name)
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to watch the files of program:", err)
+			}
+		}
+	}()
+}
+
+// stop to watch the files of the program, call without lock.
+func (p *Process) stopWatch() {
+	p.lock.Lock()
+	w := p.watch
+	p.watch = nil
+	p.lock.Unlock()
+	if w != nil {
+		w.close()
+	}
+}
+
+// apply the watch_action after the watched file is changed
+func (p *Process) applyWatchAction(w *fileWatch, name string) {
+	p.lock.RLock()
+	current := p.watch == w
+	p.lock.RUnlock()
+	if !current {
+		return
+	}
+	action, sig, err := p.getWatchAction()
+	if err != nil {
+		log.WithFields(log.Fields{"program": p.GetName()}).Error(err)
+		return
+	}
+	log.WithFields(log.Fields{"program": p.GetName(), "file": name, "action": action}).Info("the watched file of program is changed")
+	switch {
+	case action == "restart":
+		p.Restart(false)
+	case action == "reload":
+		if err := p.Reload(); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName()}).Warn("fail to reload the program:", err)
+		}
+	default:
+		if err := p.Signal(sig, p.config.GetBool("stopasgroup", false)); err != nil {
+			log.WithFields(log.Fields{"program": p.GetName(), "action": action}).Warn("fail to signal the program:", err)
+		}
+	}
+}
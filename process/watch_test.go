@@ -0,0 +1,49 @@
+// +build !windows
+
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatchPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	proc := newTestProcess(t, dir, `[program:watch]
+command=cat -
+directory=`+dir+`
+watch_paths=conf/*.conf, /etc/watch.yaml
+watch_action=signal:HUP
+`)
+	if !proc.isWatched() {
+		t.Fatal("expect the files are watched")
+	}
+	patterns, err := proc.getWatchPatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 3 || filepath.Base(patterns[0]) != "cat" || patterns[1] != filepath.Join(dir, "conf/*.conf") || patterns[2] != "/etc/watch.yaml" {
+		t.Fatalf("Fail to get the watched patterns: %v", patterns)
+	}
+	w := &fileWatch{patterns: patterns}
+	if !w.match(filepath.Join(dir, "conf/a.conf")) || w.match(filepath.Join(dir, "conf/a.yaml")) || !w.match(patterns[0]) {
+		t.Error("Fail to match the watched files")
+	}
+	if action, sig, err := proc.getWatchAction(); err != nil || action != "signal:HUP" || sig == nil {
+		t.Errorf("Fail to get the watch action: %s, %v, %v", action, sig, err)
+	}
+
+	proc = newTestProcess(t, dir, `[program:watch]
+command=/bin/cat
+watch_action=signal:NOSUCH
+`)
+	if _, _, err := proc.getWatchAction(); err == nil {
+		t.Error("expect the signal is not supported")
+	}
+}
@@ -0,0 +1,302 @@
+package supd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaycc/supd/process"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the proxy listens on one address and balances the connections or the requests
+// across the RUNNING instances of the program by their allocated ports:
+//
+//  [proxy:web]
+//  listen=0.0.0.0:8000
+//  program=web
+//  mode=tcp                    - tcp balances the connections, http balances the requests
+//  backend_host=127.0.0.1
+//  health_check=http:/healthz  - tcp, http:<path> or empty for no check
+//  health_check_interval=5
+//  health_check_timeout=2
+//
+// The instance is removed from the backends when it leaves RUNNING or fails the health check.
+type proxyServer struct {
+	s     *Supervisor
+	name  string
+	conf  string
+	entry *config.ConfigEntry
+
+	listener net.Listener
+	server   *http.Server
+	next     uint32
+
+	lock sync.RWMutex
+	// the instances failed the health check
+	unhealthy map[string]bool
+	closed    chan struct{}
+}
+
+// a backend of the proxy
+type proxyBackend struct {
+	name string
+	addr string
+}
+
+func (s *Supervisor) newProxyServer(entry *config.ConfigEntry) (*proxyServer, error) {
+	p := &proxyServer{
+		s:         s,
+		name:      entry.GetProxyName(),
+		conf:      entry.String(),
+		entry:     entry,
+		unhealthy: map[string]bool{},
+		closed:    make(chan struct{}),
+	}
+	if entry.GetString("program", "") == "" {
+		return nil, errors.New("the program of proxy is not configured").As(p.name)
+	}
+	mode := p.getMode()
+	if mode != "tcp" && mode != "http" {
+		return nil, errors.New("unsupported mode of proxy").As(p.name, mode)
+	}
+	addr := entry.GetString("listen", "")
+	if addr == "" {
+		return nil, errors.New("the listen address of proxy is not configured").As(p.name)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.As(err, p.name, addr)
+	}
+	p.listener = ln
+	return p, nil
+}
+
+func (p *proxyServer) getMode() string {
+	return p.entry.GetString("mode", "tcp")
+}
+
+// get the RUNNING and healthy instances of the program in the order of the names
+func (p *proxyServer) getBackends() []proxyBackend {
+	program := p.entry.GetString("program", "")
+	host := p.entry.GetString("backend_host", "127.0.0.1")
+	backends := []proxyBackend{}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	for _, proc := range p.s.findProgramInstances(program) {
+		port := proc.GetConfig().GetString("port", "")
+		if port == "" || proc.GetState() != process.RUNNING || p.unhealthy[proc.GetName()] {
+			continue
+		}
+		backends = append(backends, proxyBackend{name: proc.GetName(), addr: net.JoinHostPort(host, port)})
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].name < backends[j].name
+	})
+	return backends
+}
+
+// pick the backends by round robin, the first one is used and the others are for retrying.
+func (p *proxyServer) pickBackends() []proxyBackend {
+	backends := p.getBackends()
+	if len(backends) == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(&p.next, 1)) % len(backends)
+	return append(backends[start:], backends[:start]...)
+}
+
+func (p *proxyServer) start() {
+	log.WithFields(log.Fields{"proxy": p.name, "listen": p.listener.Addr().String(), "mode": p.getMode()}).Info("start the proxy")
+	go p.runHealthCheck()
+	if p.getMode() == "http" {
+		p.server = &http.Server{Handler: &httputil.ReverseProxy{
+			Director: func(req *http.Request) {
+				req.URL.Scheme = "http"
+				if backends := p.pickBackends(); len(backends) > 0 {
+					req.URL.Host = backends[0].addr
+				}
+			},
+			ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+				log.WithFields(log.Fields{"proxy": p.name, "backend": req.URL.Host}).Warn("fail to proxy the request:", err)
+				w.WriteHeader(http.StatusBadGateway)
+			},
+		}}
+		go p.server.Serve(p.listener)
+		return
+	}
+	go func() {
+		for {
+			conn, err := p.listener.Accept()
+			if err != nil {
+				select {
+				case <-p.closed:
+					return
+				default:
+				}
+				log.WithFields(log.Fields{"proxy": p.name}).Warn("fail to accept the connection:", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			go p.serveConn(conn)
+		}
+	}()
+}
+
+// forward the connection to the first backend which can be connected
+func (p *proxyServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	var backendConn net.Conn
+	for _, backend := range p.pickBackends() {
+		c, err := net.DialTimeout("tcp", backend.addr, p.getHealthCheckTimeout())
+		if err == nil {
+			backendConn = c
+			break
+		}
+		log.WithFields(log.Fields{"proxy": p.name, "backend": backend.name}).Warn("fail to connect the backend:", err)
+	}
+	if backendConn == nil {
+		log.WithFields(log.Fields{"proxy": p.name}).Warn("no backend is available")
+		return
+	}
+	defer backendConn.Close()
+	done := make(chan struct{}, 2)
+	pipe := func(dst net.Conn, src net.Conn) {
+		io.Copy(dst, src)
+		// let the other side know the end of the stream
+		if tcpConn, ok := dst.(*net.TCPConn); ok {
+			tcpConn.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+	go pipe(backendConn, conn)
+	go pipe(conn, backendConn)
+	<-done
+	<-done
+}
+
+func (p *proxyServer) getHealthCheckTimeout() time.Duration {
+	return time.Duration(p.entry.GetInt("health_check_timeout", 2)) * time.Second
+}
+
+// check the RUNNING instances in every health_check_interval seconds
+func (p *proxyServer) runHealthCheck() {
+	check := p.entry.GetString("health_check", "")
+	if check == "" {
+		return
+	}
+	interval := time.Duration(p.entry.GetInt("health_check_interval", 5)) * time.Second
+	host := p.entry.GetString("backend_host", "127.0.0.1")
+	for {
+		unhealthy := map[string]bool{}
+		for _, proc := range p.s.findProgramInstances(p.entry.GetString("program", "")) {
+			port := proc.GetConfig().GetString("port", "")
+			if port == "" || proc.GetState() != process.RUNNING {
+				continue
+			}
+			if err := p.checkBackend(check, net.JoinHostPort(host, port)); err != nil {
+				log.WithFields(log.Fields{"proxy": p.name, "backend": proc.GetName()}).Warn("the backend fails the health check:", err)
+				unhealthy[proc.GetName()] = true
+			}
+		}
+		p.lock.Lock()
+		p.unhealthy = unhealthy
+		p.lock.Unlock()
+
+		select {
+		case <-p.closed:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// check the backend by connecting it or by the http request
+func (p *proxyServer) checkBackend(check string, addr string) error {
+	timeout := p.getHealthCheckTimeout()
+	switch {
+	case check == "tcp":
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return errors.As(err, addr)
+		}
+		conn.Close()
+		return nil
+	case strings.HasPrefix(check, "http:"):
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(fmt.Sprintf("http://%s%s", addr, strings.TrimPrefix(check, "http:")))
+		if err != nil {
+			return errors.As(err, addr)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return errors.New("bad status of health check").As(addr, resp.StatusCode)
+		}
+		return nil
+	}
+	return errors.New("unsupported health_check").As(check)
+}
+
+func (p *proxyServer) stop() {
+	log.WithFields(log.Fields{"proxy": p.name}).Info("stop the proxy")
+	close(p.closed)
+	if p.server != nil {
+		p.server.Close()
+		return
+	}
+	p.listener.Close()
+}
+
+// get the instances of the numprocs program, or the program of the name
+func (s *Supervisor) findProgramInstances(program string) []*process.Process {
+	return s.getAllProcesses(func(proc *process.Process) bool {
+		return proc.GetConfig().Program == program || proc.GetName() == program
+	})
+}
+
+// start the configured proxies, the changed proxies are restarted and the removed ones are stopped.
+func (s *Supervisor) startProxies() {
+	configured := map[string]bool{}
+	for _, entry := range s.config.GetProxies() {
+		name := entry.GetProxyName()
+		configured[name] = true
+		if p, ok := s.proxies[name]; ok {
+			if p.conf == entry.String() {
+				continue
+			}
+			p.stop()
+			delete(s.proxies, name)
+		}
+		p, err := s.newProxyServer(entry)
+		if err != nil {
+			log.WithFields(log.Fields{"proxy": name}).Error("fail to start the proxy:", err)
+			continue
+		}
+		p.start()
+		s.proxies[name] = p
+	}
+	for name, p := range s.proxies {
+		if !configured[name] {
+			p.stop()
+			delete(s.proxies, name)
+		}
+	}
+}
+
+func (s *Supervisor) stopProxies() {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	for name, p := range s.proxies {
+		p.stop()
+		delete(s.proxies, name)
+	}
+}
@@ -0,0 +1,114 @@
+package supd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gwaycc/supd/process"
+)
+
+// serve the name of the backend on the port
+func serveBackendName(t *testing.T, port string, name string) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(name))
+			conn.Close()
+		}
+	}()
+	return ln
+}
+
+// get the backend names answered through the proxy
+func dialProxy(t *testing.T, addr string, times int) map[string]int {
+	names := map[string]int{}
+	for i := 0; i < times; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(conn)
+		conn.Close()
+		names[string(data)]++
+	}
+	return names
+}
+
+func TestProxy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(`[program:web]
+command=/bin/sleep 30
+process_name=web_%(process_num)d
+numprocs=2
+port_range=39100-39199
+startsecs=0
+autostart=false
+
+[proxy:web]
+listen=127.0.0.1:0
+program=web
+health_check=tcp
+health_check_interval=1
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	backends := map[string]net.Listener{}
+	for _, entry := range s.config.GetPrograms() {
+		proc := s.createProcess(entry)
+		proc.Start(true)
+		defer proc.Stop(true)
+		backends[proc.GetName()] = serveBackendName(t, entry.GetString("port", ""), proc.GetName())
+		defer backends[proc.GetName()].Close()
+	}
+	s.startProxies()
+	defer s.stopProxies()
+	p, ok := s.proxies["web"]
+	if !ok {
+		t.Fatal("the proxy is not started")
+	}
+	addr := p.listener.Addr().String()
+	for i := 0; i < 50 && len(p.getBackends()) < 2; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	names := dialProxy(t, addr, 4)
+	if names["web_1"] != 2 || names["web_2"] != 2 {
+		t.Fatalf("expect the connections balanced, but got %v", names)
+	}
+
+	// the instance failed the health check is removed
+	backends["web_2"].Close()
+	time.Sleep(2 * time.Second)
+	if names := dialProxy(t, addr, 2); names["web_1"] != 2 {
+		t.Fatalf("expect web_1 only, but got %v", names)
+	}
+	if s.procMgr.Find("web_2").GetState() != process.RUNNING {
+		t.Fatal("expect web_2 is still RUNNING")
+	}
+
+	// the instance left RUNNING is removed
+	s.procMgr.Find("web_1").Stop(true)
+	if names := dialProxy(t, addr, 1); names[""] != 1 {
+		t.Fatalf("expect no backend, but got %v", names)
+	}
+}
@@ -2,6 +2,7 @@ package rpcclient
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/gwaycc/supd/types"
 	"github.com/gwaylib/errors"
@@ -102,6 +103,54 @@ func (r *RPCClient) ChangeAllProcessState(change string) (*ChangeAllProcessState
 	return ret, nil
 }
 
+type RollingRestartArg struct {
+	Name string
+	// the number of the processes restarted in a batch
+	MaxUnavailable int
+	// the pause between the batches
+	Pause time.Duration
+}
+type RollingRestartRet AllProcessInfoReply
+
+func (r *RPCClient) RollingRestart(in *RollingRestartArg) (*RollingRestartRet, error) {
+	ret := &RollingRestartRet{}
+	if err := r.call("Supervisor.RollingRestart", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
+type ScaleProgramArg struct {
+	Name     string
+	Numprocs int
+}
+type ScaleProgramRet struct {
+	Added   []string
+	Removed []string
+}
+
+func (r *RPCClient) ScaleProgram(in *ScaleProgramArg) (*ScaleProgramRet, error) {
+	ret := &ScaleProgramRet{}
+	if err := r.call("Supervisor.ScaleProgram", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
+type RemoveInstanceArg struct {
+	Name string
+	Wait bool
+}
+type RemoveInstanceRet StatusReply
+
+func (r *RPCClient) RemoveInstance(in *RemoveInstanceArg) (*RemoveInstanceRet, error) {
+	ret := &RemoveInstanceRet{}
+	if err := r.call("Supervisor.RemoveInstance", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
 type ShutdownArg struct {
 }
 type ShutdownRet struct {
@@ -133,6 +182,72 @@ func (r *RPCClient) ReloadConfig() (*ReloadConfigRet, error) {
 	return ret, nil
 }
 
+type ReloadProcessArg struct {
+	Name string
+}
+type ReloadProcessRet StatusReply
+
+func (r *RPCClient) ReloadProcess(in *ReloadProcessArg) (*ReloadProcessRet, error) {
+	ret := &ReloadProcessRet{}
+	if err := r.call("Supervisor.ReloadProcess", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
+type DisableProcessArg struct {
+	Name string
+	Wait bool
+}
+type DisableProcessRet StatusReply
+
+func (r *RPCClient) DisableProcess(in *DisableProcessArg) (*DisableProcessRet, error) {
+	ret := &DisableProcessRet{}
+	if err := r.call("Supervisor.DisableProcess", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
+type EnableProcessArg struct {
+	Name string
+}
+type EnableProcessRet StatusReply
+
+func (r *RPCClient) EnableProcess(in *EnableProcessArg) (*EnableProcessRet, error) {
+	ret := &EnableProcessRet{}
+	if err := r.call("Supervisor.EnableProcess", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
+type PauseProcessArg struct {
+	Name string
+}
+type PauseProcessRet StatusReply
+
+func (r *RPCClient) PauseProcess(in *PauseProcessArg) (*PauseProcessRet, error) {
+	ret := &PauseProcessRet{}
+	if err := r.call("Supervisor.PauseProcess", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
+type ResumeProcessArg struct {
+	Name string
+}
+type ResumeProcessRet StatusReply
+
+func (r *RPCClient) ResumeProcess(in *ResumeProcessArg) (*ResumeProcessRet, error) {
+	ret := &ResumeProcessRet{}
+	if err := r.call("Supervisor.ResumeProcess", in, ret); err != nil {
+		return nil, errors.As(err)
+	}
+	return ret, nil
+}
+
 type SignalProcessArg struct {
 	ProcName string
 	Signal   string
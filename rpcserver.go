@@ -124,8 +124,9 @@ func (p *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 var (
-	rpcAuthHandle     *httpBasicAuth
-	programAuthHandle *httpBasicAuth
+	rpcAuthHandle       *httpBasicAuth
+	programAuthHandle   *httpBasicAuth
+	discoveryAuthHandle *httpBasicAuth
 )
 
 func (p *RPCServer) startHttpServer(user string, password string, protocol string, listenAddr string) {
@@ -150,6 +151,14 @@ func (p *RPCServer) startHttpServer(user string, password string, protocol strin
 		programAuthHandle.SetAuth(user, password, prog_rest_handler)
 	}
 
+	discovery_handler := http.HandlerFunc(s.serveDiscovery)
+	if discoveryAuthHandle == nil {
+		discoveryAuthHandle = NewHttpBasicAuth(user, password, discovery_handler)
+		HttpMux.Handle("/discovery", discoveryAuthHandle)
+	} else {
+		discoveryAuthHandle.SetAuth(user, password, discovery_handler)
+	}
+
 	httpServer, ok := p.listeners[protocol]
 	if ok {
 		if err := httpServer.Close(); err != nil {
@@ -0,0 +1,10 @@
+package signals
+
+import (
+	"strings"
+)
+
+// normalize the signal name, e.g. "sighup" and "SIGHUP" are "HUP"
+func normalizeName(signalName string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(signalName)), "SIG")
+}
@@ -3,12 +3,14 @@
 package signals
 
 import (
+	"fmt"
 	"os"
 	"syscall"
 )
 
-//convert a signal name to signal
+//convert a signal name to signal, the name can be like HUP, SIGHUP or hup
 func ToSignal(signalName string) (os.Signal, error) {
+	signalName = normalizeName(signalName)
 	if signalName == "HUP" {
 		return syscall.SIGHUP, nil
 	} else if signalName == "INT" {
@@ -21,9 +23,10 @@ func ToSignal(signalName string) (os.Signal, error) {
 		return syscall.SIGUSR1, nil
 	} else if signalName == "USR2" {
 		return syscall.SIGUSR2, nil
-	} else {
+	} else if signalName == "TERM" {
 		return syscall.SIGTERM, nil
-
+	} else {
+		return nil, fmt.Errorf("unsupported signal %s", signalName)
 	}
 
 }
@@ -11,8 +11,9 @@ import (
 	"syscall"
 )
 
-//convert a signal name to signal
+//convert a signal name to signal, the name can be like HUP, SIGHUP or hup
 func ToSignal(signalName string) (os.Signal, error) {
+	signalName = normalizeName(signalName)
 	if signalName == "HUP" {
 		return syscall.SIGHUP, nil
 	} else if signalName == "INT" {
@@ -27,9 +28,10 @@ func ToSignal(signalName string) (os.Signal, error) {
 	} else if signalName == "USR2" {
 		log.Warn("signal USR2 is not supported in windows")
 		return nil, errors.New("signal USR2 is not supported in windows")
-	} else {
+	} else if signalName == "TERM" {
 		return syscall.SIGTERM, nil
-
+	} else {
+		return nil, fmt.Errorf("unsupported signal %s", signalName)
 	}
 
 }
@@ -0,0 +1,142 @@
+package supd
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaycc/supd/process"
+	log "github.com/sirupsen/logrus"
+)
+
+// the limit of the bulk start, at most concurrency processes are STARTING at the same time
+// and the processes are started stagger apart. zero means no limit.
+type startLimit struct {
+	concurrency int
+	stagger     time.Duration
+}
+
+func (l startLimit) isLimited() bool {
+	return l.concurrency > 0 || l.stagger > 0
+}
+
+// get the start limit of the [supervisord] or [group:x] section
+//
+//  start_concurrency=10
+//  start_stagger=500ms
+func getStartLimit(entry *config.ConfigEntry) startLimit {
+	if entry == nil {
+		return startLimit{}
+	}
+	return startLimit{
+		concurrency: entry.GetInt("start_concurrency", 0),
+		stagger:     parseStagger(entry.GetString("start_stagger", "")),
+	}
+}
+
+// parse the stagger like 500ms or 2s, the number without unit is in seconds.
+func parseStagger(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	stagger, err := time.ParseDuration(value)
+	if err != nil {
+		log.WithFields(log.Fields{"start_stagger": value}).Warn("invalid start_stagger, ignore it")
+		return 0
+	}
+	return stagger
+}
+
+// the slots of the bulk start, the slot is acquired by the dispatcher and released by the worker.
+type startSlots struct {
+	limit startLimit
+	slots chan struct{}
+	last  time.Time
+}
+
+func newStartSlots(limit startLimit) *startSlots {
+	s := &startSlots{limit: limit}
+	if limit.concurrency > 0 {
+		s.slots = make(chan struct{}, limit.concurrency)
+	}
+	return s
+}
+
+// wait for a free slot and the stagger after the last start, call by the dispatcher only.
+func (s *startSlots) acquire() {
+	if s.slots != nil {
+		s.slots <- struct{}{}
+	}
+	if wait := s.last.Add(s.limit.stagger).Sub(time.Now()); wait > 0 {
+		time.Sleep(wait)
+	}
+	s.last = time.Now()
+}
+
+func (s *startSlots) release() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// start the processes in the priority order through the start limits of [supervisord] and the groups.
+//
+// The process holds its slots until it leaves STARTING, so the action is called with wait=true
+// if the process is limited. It returns after all the processes are started if wait is true,
+// or the processes are started in background.
+func (s *Supervisor) startInOrder(procs []*process.Process, wait bool, action func(proc *process.Process, wait bool)) {
+	procs = append([]*process.Process{}, procs...)
+	sort.SliceStable(procs, func(i, j int) bool {
+		return procs[i].GetPriority() < procs[j].GetPriority()
+	})
+
+	var supervisordEntry *config.ConfigEntry
+	if entry, ok := s.config.GetSupervisord(); ok {
+		supervisordEntry = entry
+	}
+	global := newStartSlots(getStartLimit(supervisordEntry))
+	groups := map[string]*startSlots{}
+	for _, entry := range s.config.GetGroups() {
+		if limit := getStartLimit(entry); limit.isLimited() {
+			groups[entry.GetGroupName()] = newStartSlots(limit)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(procs))
+	dispatch := func() {
+		for _, proc := range procs {
+			group := groups[proc.GetGroup()]
+			if !global.limit.isLimited() && group == nil {
+				go func(proc *process.Process) {
+					defer wg.Done()
+					action(proc, wait)
+				}(proc)
+				continue
+			}
+			global.acquire()
+			if group != nil {
+				group.acquire()
+			}
+			go func(proc *process.Process) {
+				defer wg.Done()
+				action(proc, true)
+				if group != nil {
+					group.release()
+				}
+				global.release()
+			}(proc)
+		}
+	}
+	if !wait {
+		go dispatch()
+		return
+	}
+	dispatch()
+	wg.Wait()
+}
@@ -0,0 +1,123 @@
+package supd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gwaycc/supd/process"
+)
+
+func TestParseStagger(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":      0,
+		"2":     2 * time.Second,
+		"0.5":   500 * time.Millisecond,
+		"300ms": 300 * time.Millisecond,
+		"1m":    time.Minute,
+		"bad":   0,
+	}
+	for value, expect := range cases {
+		if stagger := parseStagger(value); stagger != expect {
+			t.Errorf("%q: expect %s, but got %s", value, expect, stagger)
+		}
+	}
+}
+
+func TestStartInOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(`[supervisord]
+start_concurrency=2
+start_stagger=50ms
+
+[program:a]
+command=/bin/cat
+priority=3
+
+[program:b]
+command=/bin/cat
+priority=1
+
+[program:c]
+command=/bin/cat
+priority=2
+
+[program:d]
+command=/bin/cat
+priority=5
+
+[program:e]
+command=/bin/cat
+priority=4
+
+[group:g]
+programs=d,e
+start_concurrency=1
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range s.config.GetPrograms() {
+		s.createProcess(entry)
+	}
+
+	var lock sync.Mutex
+	order := []string{}
+	starts := []time.Time{}
+	running, maxRunning, maxGroupRunning := 0, 0, 0
+	groupRunning := 0
+	s.startInOrder(s.getAllProcesses(func(proc *process.Process) bool { return true }), true, func(proc *process.Process, wait bool) {
+		if !wait {
+			t.Errorf("expect the limited process %s is started with wait", proc.GetName())
+		}
+		lock.Lock()
+		order = append(order, proc.GetName())
+		starts = append(starts, time.Now())
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		if proc.GetGroup() == "g" {
+			groupRunning++
+			if groupRunning > maxGroupRunning {
+				maxGroupRunning = groupRunning
+			}
+		}
+		lock.Unlock()
+
+		time.Sleep(200 * time.Millisecond)
+
+		lock.Lock()
+		running--
+		if proc.GetGroup() == "g" {
+			groupRunning--
+		}
+		lock.Unlock()
+	})
+
+	if len(order) != 5 || order[0] != "b" || order[1] != "c" || order[2] != "a" || order[3] != "e" || order[4] != "d" {
+		t.Fatalf("expect the processes are started in the priority order, but got %v", order)
+	}
+	if maxRunning > 2 {
+		t.Fatalf("expect at most 2 processes are starting, but got %d", maxRunning)
+	}
+	if maxGroupRunning > 1 {
+		t.Fatalf("expect at most 1 process of the group is starting, but got %d", maxGroupRunning)
+	}
+	for i := 1; i < len(starts); i++ {
+		if starts[i].Sub(starts[i-1]) < 40*time.Millisecond {
+			t.Fatalf("expect the processes are started 50ms apart, but got %s", starts[i].Sub(starts[i-1]))
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package supd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gwaycc/supd/config"
+	"github.com/gwaycc/supd/events"
+	"github.com/gwaycc/supd/process"
+	"github.com/gwaycc/supd/util"
+	"github.com/gwaylib/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the runtime state of supd which is kept across the restarts of supd
+type supervisorState struct {
+	// the running children which can be adopted by the next supd
+	Children []*process.ChildState `json:"children,omitempty"`
+	// the runtime intent of the programs
+	Programs map[string]*programState `json:"programs,omitempty"`
+	// the environment set by the ctl setenv
+	Environment map[string]string `json:"environment,omitempty"`
+}
+
+// the runtime intent of a program which overrides the configuration
+type programState struct {
+	// stopped by the user, it is not started automatically until the user starts it
+	Stopped bool `json:"stopped,omitempty"`
+	// the numprocs scaled by the user, nil if it is not scaled
+	Numprocs *int `json:"numprocs,omitempty"`
+	// the numprocs in the configuration when it is scaled, the scaled numprocs is dropped if it is changed
+	ConfigNumprocs *int `json:"config_numprocs,omitempty"`
+	// the program is an instance created from a template by the user
+	Instance bool `json:"instance,omitempty"`
+	// disabled by the user, it can't be started until the user enables it
+	Disabled bool `json:"disabled,omitempty"`
+	// the port allocated by port_range, it is kept across the restarts
+	Port int `json:"port,omitempty"`
+}
+
+// get the state file, it is in the directory of the pidfile by default.
+func (s *Supervisor) getStateFile() string {
+	env := config.NewStringExpression("here", s.config.GetConfigFileDir())
+	entry, ok := s.config.GetSupervisord()
+	if !ok {
+		return ""
+	}
+	pidfile, err := env.Eval(entry.GetString("pidfile", "supervisord.pid"))
+	if err != nil {
+		return ""
+	}
+	stateFile, err := env.Eval(entry.GetString("statefile", filepath.Join(filepath.Dir(pidfile), "supd.state")))
+	if err != nil {
+		return ""
+	}
+	return stateFile
+}
+
+// true if the children keep running after supd exits and are adopted by the next supd.
+func (s *Supervisor) isAdoptChildren() bool {
+	entry, ok := s.config.GetSupervisord()
+	return ok && entry.GetBool("adopt_children", false)
+}
+
+// get the directory of the log fifos of the adoptable children
+func (s *Supervisor) getAdoptDir() string {
+	if !s.isAdoptChildren() {
+		return ""
+	}
+	stateFile := s.getStateFile()
+	if stateFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(stateFile), "supd.fifo")
+}
+
+// get the directory of the notify sockets of the programs
+func (s *Supervisor) getNotifyDir() string {
+	stateFile := s.getStateFile()
+	if stateFile == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(stateFile), "supd.notify")
+}
+
+func loadState(file string) (*supervisorState, error) {
+	state := &supervisorState{}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, errors.As(err, file)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.As(err, file)
+	}
+	return state, nil
+}
+
+// save the runtime state to the state file
+func (s *Supervisor) saveState() error {
+	file := s.getStateFile()
+	if file == "" {
+		return nil
+	}
+	state := &supervisorState{}
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		if child := proc.GetChildState(); child != nil {
+			state.Children = append(state.Children, child)
+		}
+	})
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	state.Programs = s.programStates
+	state.Environment = s.runtimeEnv
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.As(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return errors.As(err, file)
+	}
+	// replace the file at once, so a crash never leaves a broken state file
+	tmpFile := file + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		return errors.As(err, tmpFile)
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		return errors.As(err, file)
+	}
+	return nil
+}
+
+// save the state after the state of processes changed
+func (s *Supervisor) watchState() {
+	events.RegisterEventHandler("supd-state", []string{"PROCESS_STATE"}, func(event events.Event) {
+		// the handler is called with the lock of process, notify only.
+		s.notifyStateChanged()
+	})
+	s.watchers.Add(1)
+	go func() {
+		defer s.watchers.Done()
+		for {
+			select {
+			case <-s.stateChanged:
+			case <-s.done:
+				return
+			}
+			// merge the changes in a short time
+			time.Sleep(100 * time.Millisecond)
+			if err := s.saveState(); err != nil {
+				log.Warn("fail to save state:", errors.As(err))
+			}
+		}
+	}()
+}
+
+// stop saving the state and the discovery file, the next supervisor in the same supd watches them again.
+func (s *Supervisor) stopWatchers() {
+	s.stateLock.Lock()
+	select {
+	case <-s.done:
+	default:
+		events.UnregisterEventHandler("supd-state")
+		events.UnregisterEventHandler("supd-discovery")
+		close(s.done)
+	}
+	s.stateLock.Unlock()
+	// the state being saved may not contain the detached children
+	s.watchers.Wait()
+}
+
+// save the state with the adoptable children and leave them to the next supd or the next
+// supervisor in the same supd, they are not stopped by StopAllProcesses after detached.
+// Only the state is saved if adopt_children is not enabled.
+func (s *Supervisor) detachChildren() {
+	if err := s.saveState(); err != nil {
+		log.Warn("fail to save state:", errors.As(err))
+	}
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		proc.Detach()
+	})
+}
+
+// notify the state is changed and need to be saved
+func (s *Supervisor) notifyStateChanged() {
+	select {
+	case s.stateChanged <- struct{}{}:
+	default:
+	}
+}
+
+// load the state which is left by the previous supd
+func (s *Supervisor) restoreState() {
+	s.adoptableChildren = make(map[string]*process.ChildState)
+	state, err := loadState(s.getStateFile())
+	if err != nil {
+		log.Warn("fail to load state:", errors.As(err))
+		return
+	}
+	if s.isAdoptChildren() {
+		for _, child := range state.Children {
+			s.adoptableChildren[child.Name] = child
+		}
+	}
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	for name, progState := range state.Programs {
+		s.programStates[name] = progState
+	}
+	for key, value := range state.Environment {
+		log.WithFields(log.Fields{"key": key}).Info("restore the environment set by user")
+		s.runtimeEnv[key] = value
+		os.Setenv(key, value)
+	}
+}
+
+// get the runtime intent of the program, nil if there is none.
+func (s *Supervisor) getProgramState(name string) *programState {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+	progState, ok := s.programStates[name]
+	if !ok {
+		return nil
+	}
+	cp := *progState
+	return &cp
+}
+
+// update the runtime intent of the program, the program state is removed if it is empty.
+func (s *Supervisor) updateProgramState(name string, update func(progState *programState)) {
+	s.stateLock.Lock()
+	progState, ok := s.programStates[name]
+	if !ok {
+		progState = &programState{}
+	}
+	update(progState)
+	if *progState == (programState{}) {
+		delete(s.programStates, name)
+	} else {
+		s.programStates[name] = progState
+	}
+	s.stateLock.Unlock()
+	s.notifyStateChanged()
+}
+
+// keep the numprocs scaled by the user and the numprocs in the configuration, nil if it is not scaled
+func (s *Supervisor) setScaledNumprocs(name string, numprocs *int, confNumprocs *int) {
+	s.updateProgramState(name, func(progState *programState) {
+		progState.Numprocs = numprocs
+		progState.ConfigNumprocs = confNumprocs
+	})
+}
+
+// drop the numprocs scaled by the user which is dropped by the config after the numprocs
+// in the configuration is changed
+func (s *Supervisor) syncScaledNumprocs() {
+	s.stateLock.Lock()
+	names := []string{}
+	for name, progState := range s.programStates {
+		if progState.Numprocs != nil && !s.config.IsScaled(name) {
+			names = append(names, name)
+		}
+	}
+	s.stateLock.Unlock()
+	for _, name := range names {
+		s.setScaledNumprocs(name, nil, nil)
+	}
+}
+
+// mark the program is stopped or started by the user
+func (s *Supervisor) setStoppedByUser(name string, stopped bool) {
+	s.updateProgramState(name, func(progState *programState) {
+		progState.Stopped = stopped
+	})
+}
+
+// true if the program is stopped by the user and should not be started automatically
+func (s *Supervisor) isStoppedByUser(name string) bool {
+	progState := s.getProgramState(name)
+	return progState != nil && progState.Stopped
+}
+
+// mark the program is disabled or enabled by the user
+func (s *Supervisor) setDisabled(name string, disabled bool) {
+	s.updateProgramState(name, func(progState *programState) {
+		progState.Disabled = disabled
+	})
+}
+
+// true if the program is disabled by the user
+func (s *Supervisor) isDisabled(name string) bool {
+	progState := s.getProgramState(name)
+	return progState != nil && progState.Disabled
+}
+
+// apply the numprocs and the instances created by the user to the loaded programs,
+// return the loaded programs after applying.
+func (s *Supervisor) restorePrograms(loadedProgramNames []string) []string {
+	s.stateLock.Lock()
+	numprocs := map[string]int{}
+	confNumprocs := map[string]*int{}
+	instances := []string{}
+	ports := map[string]int{}
+	for name, progState := range s.programStates {
+		if progState.Port > 0 {
+			ports[name] = progState.Port
+		}
+		if progState.Numprocs != nil {
+			numprocs[name] = *progState.Numprocs
+			confNumprocs[name] = progState.ConfigNumprocs
+		}
+		if progState.Instance {
+			instances = append(instances, name)
+		}
+	}
+	s.stateLock.Unlock()
+
+	// restore the ports before scaling, the scaled instances get their ports back
+	if len(ports) > 0 {
+		s.config.SetPorts(ports)
+	}
+	for name, n := range numprocs {
+		conf, ok := s.config.GetConfigNumprocs(name)
+		if ok && confNumprocs[name] != nil && *confNumprocs[name] != conf {
+			log.WithFields(log.Fields{"program": name, "numprocs": conf}).Info("the numprocs in the configuration is changed, drop the numprocs scaled by user")
+			s.setScaledNumprocs(name, nil, nil)
+			continue
+		}
+		added, removed, err := s.config.ScaleProgram(name, n)
+		if err != nil {
+			log.WithFields(log.Fields{"program": name, "numprocs": n}).Warn("fail to restore the numprocs:", err)
+			continue
+		}
+		log.WithFields(log.Fields{"program": name, "numprocs": n, "config_numprocs": conf}).Info("restore the numprocs scaled by user, it overrides the numprocs in the configuration")
+		// the state of the older supd has no numprocs of the configuration
+		scaled := n
+		s.setScaledNumprocs(name, &scaled, &conf)
+		loadedProgramNames = append(util.Sub(loadedProgramNames, removed), added...)
+	}
+	for _, name := range instances {
+		if _, err := s.config.CreateInstance(name); err != nil {
+			log.WithFields(log.Fields{"program": name}).Warn("fail to restore the instance:", err)
+			s.updateProgramState(name, func(progState *programState) {
+				progState.Instance = false
+			})
+			continue
+		}
+		log.WithFields(log.Fields{"program": name}).Info("restore the instance created by user")
+		loadedProgramNames = append(loadedProgramNames, name)
+	}
+	return loadedProgramNames
+}
+
+// keep the ports allocated to the instances in the state, they are restored at the next start
+func (s *Supervisor) savePorts() {
+	ports := s.config.GetPorts()
+	s.stateLock.Lock()
+	stale := []string{}
+	for name, progState := range s.programStates {
+		if _, ok := ports[name]; !ok && progState.Port > 0 {
+			stale = append(stale, name)
+		}
+	}
+	s.stateLock.Unlock()
+	for name, port := range ports {
+		if progState := s.getProgramState(name); progState != nil && progState.Port == port {
+			continue
+		}
+		port := port
+		s.updateProgramState(name, func(progState *programState) {
+			progState.Port = port
+		})
+	}
+	for _, name := range stale {
+		s.updateProgramState(name, func(progState *programState) {
+			progState.Port = 0
+		})
+	}
+}
+
+// set the environment of supd and keep it across the restarts
+func (s *Supervisor) setRuntimeEnv(key, value string) {
+	s.stateLock.Lock()
+	s.runtimeEnv[key] = value
+	s.stateLock.Unlock()
+	os.Setenv(key, value)
+	s.notifyStateChanged()
+}
+
+// adopt the child of the process if it is left by the previous supd.
+//
+// Return true if the child is adopted.
+func (s *Supervisor) adoptChild(proc *process.Process) bool {
+	child, ok := s.adoptableChildren[proc.GetName()]
+	if !ok {
+		return false
+	}
+	delete(s.adoptableChildren, proc.GetName())
+	if err := proc.Adopt(child); err != nil {
+		log.WithFields(log.Fields{"program": proc.GetName()}).Info("the child can't be adopted and will be started again: ", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+// +build linux
+
+package supd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gwaycc/supd/process"
+)
+
+// wait the state of the process in seconds
+func waitProcessState(s *Supervisor, name string, state process.ProcessState, seconds int) bool {
+	for i := 0; i < seconds*10; i++ {
+		if proc := s.procMgr.Find(name); proc != nil && proc.GetState() == state {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRestartAdoptChildren(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(`[supervisord]
+logfile=/dev/stdout
+pidfile=%(here)s/supd.pid
+adopt_children=true
+
+[program:a]
+command=/bin/sleep 30
+startsecs=0
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	first := NewSupervisor(confFile)
+	if err, _, _, _ := first.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !waitProcessState(first, "a", process.RUNNING, 5) {
+		t.Fatal("expect a is RUNNING")
+	}
+	pid := first.procMgr.Find("a").GetPid()
+
+	// ctl reload of the whole supd replaces the supervisor in the same supd
+	first.restarting = true
+	first.waitForExit()
+	select {
+	case <-first.done:
+	default:
+		t.Fatal("expect the watchers of the replaced supervisor are stopped")
+	}
+	second := NewSupervisor(confFile)
+	if err, _, _, _ := second.reload(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		second.stopWatchers()
+		second.procMgr.StopAllProcesses()
+	}()
+	if !waitProcessState(second, "a", process.RUNNING, 5) {
+		t.Fatal("expect a is adopted")
+	}
+	if second.procMgr.Find("a").GetPid() != pid {
+		t.Fatalf("expect the child %d is adopted, but the pid is %d", pid, second.procMgr.Find("a").GetPid())
+	}
+
+	// the adopted child is not restarted by the replaced supervisor after it exits
+	second.procMgr.Find("a").Stop(true)
+	time.Sleep(1500 * time.Millisecond)
+	if !second.procMgr.Find("a").Stoped() {
+		t.Fatal("expect a is stopped")
+	}
+	if proc := first.procMgr.Find("a"); proc != nil && proc.GetPid() != 0 {
+		t.Fatalf("expect the replaced supervisor does not restart a, but the pid is %d", proc.GetPid())
+	}
+}
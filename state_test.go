@@ -0,0 +1,121 @@
+package supd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateSaveRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte("[supervisord]\npidfile=%(here)s/supd.pid\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("SUPD_STATE_TEST")
+
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.setStoppedByUser("foo", true)
+	s.setStoppedByUser("bar", true)
+	s.setStoppedByUser("bar", false)
+	s.setDisabled("baz", true)
+	s.setRuntimeEnv("SUPD_STATE_TEST", "1")
+	if err := s.saveState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "supd.state")); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Unsetenv("SUPD_STATE_TEST")
+	s = NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.restoreState()
+	if !s.isStoppedByUser("foo") {
+		t.Error("expect foo is stopped by user")
+	}
+	if s.isStoppedByUser("bar") {
+		t.Error("expect bar is not stopped by user")
+	}
+	if !s.isDisabled("baz") || s.isDisabled("foo") {
+		t.Error("expect only baz is disabled")
+	}
+	if os.Getenv("SUPD_STATE_TEST") != "1" {
+		t.Error("expect the environment is restored")
+	}
+}
+
+func TestRestoreScaledNumprocs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	writeConf := func(numprocs int) {
+		conf := fmt.Sprintf("[supervisord]\npidfile=%%(here)s/supd.pid\n\n[program:w]\ncommand=/bin/sleep 30\nprocess_name=w_%%(process_num)s\nnumprocs=%d\n", numprocs)
+		if err := ioutil.WriteFile(confFile, []byte(conf), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (*Supervisor, []string) {
+		s := NewSupervisor(confFile)
+		loaded, err := s.config.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.restoreState()
+		return s, s.restorePrograms(loaded)
+	}
+
+	writeConf(1)
+	s, _ := load()
+	if _, _, err := s.config.ScaleProgram("w", 3); err != nil {
+		t.Fatal(err)
+	}
+	numprocs, confNumprocs := 3, 1
+	s.setScaledNumprocs("w", &numprocs, &confNumprocs)
+	if err := s.saveState(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the scaled numprocs overrides the same configuration
+	s, loaded := load()
+	if len(loaded) != 3 {
+		t.Fatalf("expect the scaled numprocs is restored, but got %v", loaded)
+	}
+	// the scaled numprocs is dropped by reloading the changed configuration
+	writeConf(4)
+	if loaded, err = s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.syncScaledNumprocs()
+	if len(loaded) != 4 || s.getProgramState("w") != nil {
+		t.Fatalf("expect the numprocs of the configuration is used after reload, but got %v", loaded)
+	}
+
+	// the scaled numprocs is dropped by restarting with the changed configuration
+	writeConf(1)
+	s, _ = load()
+	s.config.ScaleProgram("w", 3)
+	s.setScaledNumprocs("w", &numprocs, &confNumprocs)
+	if err := s.saveState(); err != nil {
+		t.Fatal(err)
+	}
+	writeConf(2)
+	s, loaded = load()
+	if len(loaded) != 2 || s.getProgramState("w") != nil {
+		t.Fatalf("expect the numprocs of the configuration is used after restart, but got %v", loaded)
+	}
+}
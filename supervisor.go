@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/gwaycc/supd/util"
 	"github.com/gwaylib/errors"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/fsnotify.v1"
 )
 
 const (
@@ -31,6 +33,25 @@ type Supervisor struct {
 	rpcServer  *RPCServer
 	logger     logger.Logger
 	restarting bool
+	reloadLock sync.Mutex
+	// the watcher of the config files if auto_reload is enabled
+	configWatcher *fsnotify.Watcher
+	// the running [proxy:name] sections
+	proxies map[string]*proxyServer
+
+	stateLock    sync.Mutex
+	stateChanged chan struct{}
+	// notify the discovery file to be updated
+	discoveryChanged chan struct{}
+	// closed when the supervisor is replaced by ctl reload of the whole supd
+	done chan struct{}
+	// the goroutines saving the state and the discovery file
+	watchers sync.WaitGroup
+	// the children left by the previous supd, they are adopted at the first loading
+	adoptableChildren map[string]*process.ChildState
+	// the runtime intent of the programs, keep across the restarts of supd
+	programStates map[string]*programState
+	runtimeEnv    map[string]string
 }
 
 type StartProcessArgs struct {
@@ -75,6 +96,13 @@ func NewSupervisor(configFile string) *Supervisor {
 		config:     config.NewConfig(configFile),
 		procMgr:    process.NewProcessManager(),
 		restarting: false,
+
+		stateChanged:     make(chan struct{}, 1),
+		discoveryChanged: make(chan struct{}, 1),
+		done:             make(chan struct{}),
+		programStates:    make(map[string]*programState),
+		runtimeEnv:       make(map[string]string),
+		proxies:          make(map[string]*proxyServer),
 	}
 	s.rpcServer = NewRPCServer(s)
 	return s
@@ -145,7 +173,13 @@ func (s *Supervisor) ClearLog(args *struct{}, reply *rpcclient.StatusReply) erro
 
 func (s *Supervisor) Shutdown(args *struct{}, reply *rpcclient.StatusReply) error {
 	reply.Success = true
-	log.Info("received rpc request to stop all processes & exit")
+	if s.isAdoptChildren() {
+		log.Info("received rpc request to exit, the children keep running for adopting")
+	} else {
+		log.Info("received rpc request to stop all processes & exit")
+	}
+	s.stopWatchers()
+	s.detachChildren()
 	s.procMgr.StopAllProcesses()
 	go func() {
 		time.Sleep(1 * time.Second)
@@ -161,7 +195,7 @@ func (s *Supervisor) Restart(args *struct{}, reply *rpcclient.StatusReply) error
 	return nil
 }
 func (s *Supervisor) SetEnv(args *rpcclient.SetEnvArg, reply *rpcclient.SetEnvRet) error {
-	os.Setenv(args.Key, args.Value)
+	s.setRuntimeEnv(args.Key, args.Value)
 	return nil
 }
 func (s *Supervisor) GetEnv(args *rpcclient.GetEnvArg, reply *rpcclient.GetEnvRet) error {
@@ -184,7 +218,7 @@ func getProcessInfo(proc *process.Process) *types.ProcessInfo {
 		Now:           int(time.Now().Unix()),
 		State:         int(proc.GetState()),
 		Statename:     proc.GetState().String(),
-		Spawnerr:      "",
+		Spawnerr:      proc.GetSpawnErr(),
 		Exitstatus:    proc.GetExitstatus(),
 		Logfile:       proc.GetStdoutLogfile(),
 		StdoutLogfile: proc.GetStdoutLogfile(),
@@ -227,11 +261,35 @@ func (s *Supervisor) StartProcess(args *StartProcessArgs, reply *rpcclient.Statu
 
 func (s *Supervisor) startProcess(args *StartProcessArgs) error {
 	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 && config.IsInstanceName(args.Name) {
+		proc, err := s.createInstance(args.Name)
+		if err != nil {
+			return errors.As(err)
+		}
+		procs = append(procs, proc)
+	}
 	if len(procs) <= 0 {
 		return errors.New("fail to find process").As(args.Name)
 	}
+	// the other members of the group are started even if one is disabled or skipped
+	disabled := []string{}
+	skipped := []string{}
 	for _, proc := range procs {
+		if proc.IsDisabled() {
+			disabled = append(disabled, proc.GetName())
+			continue
+		}
+		s.setStoppedByUser(proc.GetName(), false)
 		proc.Start(args.Wait)
+		if reason := proc.GetSkipReason(); reason != "" {
+			skipped = append(skipped, proc.GetName()+": "+reason)
+		}
+	}
+	if len(disabled) > 0 {
+		return errors.New("the program is disabled").As(disabled, skipped)
+	}
+	if len(skipped) > 0 {
+		return errors.New("the program is skipped").As(skipped)
 	}
 	return nil
 }
@@ -247,48 +305,55 @@ func (s *Supervisor) StartAllProcesses(args *struct {
 	return nil
 }
 func (s *Supervisor) startAllProcesses(wait bool) ([]types.ProcessInfo, error) {
-	finishedProcCh := make(chan *process.Process)
 	result := []types.ProcessInfo{}
 
-	n := s.procMgr.AsyncForEachProcess(func(proc *process.Process) {
+	procs := s.getAllProcesses(func(proc *process.Process) bool { return true })
+	s.startInOrder(procs, wait, func(proc *process.Process, wait bool) {
+		s.setStoppedByUser(proc.GetName(), false)
 		proc.Start(wait)
-	}, finishedProcCh)
+	})
 
-	for i := 0; i < n; i++ {
-		proc, ok := <-finishedProcCh
-		if ok {
-			processInfo := *getProcessInfo(proc)
-			result = append(result, types.ProcessInfo{
-				Name:        processInfo.Name,
-				Group:       processInfo.Group,
-				State:       faults.SUCCESS,
-				Description: "OK",
-			})
-		}
+	for _, proc := range procs {
+		processInfo := *getProcessInfo(proc)
+		result = append(result, types.ProcessInfo{
+			Name:        processInfo.Name,
+			Group:       processInfo.Group,
+			State:       faults.SUCCESS,
+			Description: "OK",
+		})
 	}
 	return result, nil
 }
 
 func (s *Supervisor) StartProcessGroup(args *StartProcessArgs, reply *rpcclient.AllProcessInfoReply) error {
 	log.WithFields(log.Fields{"group": args.Name}).Info("start process group")
-	finishedProcCh := make(chan *process.Process)
 
-	n := s.procMgr.AsyncForEachProcess(func(proc *process.Process) {
-		if proc.GetGroup() == args.Name {
-			proc.Start(args.Wait)
-		}
-	}, finishedProcCh)
+	procs := s.getAllProcesses(func(proc *process.Process) bool {
+		return proc.GetGroup() == args.Name
+	})
+	s.startInOrder(procs, args.Wait, func(proc *process.Process, wait bool) {
+		s.setStoppedByUser(proc.GetName(), false)
+		proc.Start(wait)
+	})
 
-	for i := 0; i < n; i++ {
-		proc, ok := <-finishedProcCh
-		if ok && proc.GetGroup() == args.Name {
-			reply.AllProcessInfo = append(reply.AllProcessInfo, *getProcessInfo(proc))
-		}
+	for _, proc := range procs {
+		reply.AllProcessInfo = append(reply.AllProcessInfo, *getProcessInfo(proc))
 	}
 
 	return nil
 }
 
+// get the processes in the priority order which are accepted by the filter
+func (s *Supervisor) getAllProcesses(filter func(proc *process.Process) bool) []*process.Process {
+	procs := []*process.Process{}
+	s.procMgr.ForEachProcess(func(proc *process.Process) {
+		if filter(proc) {
+			procs = append(procs, proc)
+		}
+	})
+	return procs
+}
+
 func (s *Supervisor) StopProcess(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
 	if err := s.stopProcess(args); err != nil {
 		return errors.As(err)
@@ -303,6 +368,7 @@ func (s *Supervisor) stopProcess(args *StartProcessArgs) error {
 		return errors.New("fail to find process").As(args.Name)
 	}
 	for _, proc := range procs {
+		s.setStoppedByUser(proc.GetName(), true)
 		proc.Stop(args.Wait)
 	}
 	return nil
@@ -313,6 +379,7 @@ func (s *Supervisor) StopProcessGroup(args *StartProcessArgs, reply *rpcclient.A
 	finishedProcCh := make(chan *process.Process)
 	n := s.procMgr.AsyncForEachProcess(func(proc *process.Process) {
 		if proc.GetGroup() == args.Name {
+			s.setStoppedByUser(proc.GetName(), true)
 			proc.Stop(args.Wait)
 		}
 	}, finishedProcCh)
@@ -341,6 +408,7 @@ func (s *Supervisor) stopAllProcesses(wait bool) ([]types.ProcessInfo, error) {
 	finishedProcCh := make(chan *process.Process)
 
 	n := s.procMgr.AsyncForEachProcess(func(proc *process.Process) {
+		s.setStoppedByUser(proc.GetName(), true)
 		proc.Stop(wait)
 	}, finishedProcCh)
 
@@ -372,25 +440,22 @@ func (s *Supervisor) restartProcess(args *StartProcessArgs) error {
 	return nil
 }
 func (s *Supervisor) restartAllProcesses(wait bool) ([]types.ProcessInfo, error) {
-	finishedProcCh := make(chan *process.Process)
 	result := []types.ProcessInfo{}
 
-	n := s.procMgr.AsyncForEachProcess(func(proc *process.Process) {
-		proc.Stop(true)
-		proc.Start(wait)
-	}, finishedProcCh)
+	procs := s.getAllProcesses(func(proc *process.Process) bool { return true })
+	s.startInOrder(procs, wait, func(proc *process.Process, wait bool) {
+		s.setStoppedByUser(proc.GetName(), false)
+		proc.Restart(wait)
+	})
 
-	for i := 0; i < n; i++ {
-		proc, ok := <-finishedProcCh
-		if ok {
-			processInfo := *getProcessInfo(proc)
-			result = append(result, types.ProcessInfo{
-				Name:        processInfo.Name,
-				Group:       processInfo.Group,
-				State:       faults.SUCCESS,
-				Description: "OK",
-			})
-		}
+	for _, proc := range procs {
+		processInfo := *getProcessInfo(proc)
+		result = append(result, types.ProcessInfo{
+			Name:        processInfo.Name,
+			Group:       processInfo.Group,
+			State:       faults.SUCCESS,
+			Description: "OK",
+		})
 	}
 	return result, nil
 }
@@ -414,6 +479,87 @@ func (s *Supervisor) RestartAllProcesses(args *struct {
 	return nil
 }
 
+// restart the instances of a numprocs program or the members of a group in batches,
+// the next batch is restarted after all the processes of the batch are RUNNING.
+//
+// It aborts if a process of the batch fails to start.
+func (s *Supervisor) RollingRestart(args *rpcclient.RollingRestartArg, reply *rpcclient.RollingRestartRet) error {
+	procs := s.findRollingProcesses(args.Name)
+	if len(procs) <= 0 {
+		return errors.New("fail to find process").As(args.Name)
+	}
+	batchSize := args.MaxUnavailable
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	log.WithFields(log.Fields{"program": args.Name, "max-unavailable": batchSize, "pause": args.Pause}).Info("rolling restart")
+	for i := 0; i < len(procs); i += batchSize {
+		end := i + batchSize
+		if end > len(procs) {
+			end = len(procs)
+		}
+		batch := procs[i:end]
+		var wg sync.WaitGroup
+		for _, proc := range batch {
+			wg.Add(1)
+			go func(proc *process.Process) {
+				defer wg.Done()
+				s.setStoppedByUser(proc.GetName(), false)
+				proc.Restart(true)
+			}(proc)
+		}
+		wg.Wait()
+
+		failed := []string{}
+		for _, proc := range batch {
+			reply.AllProcessInfo = append(reply.AllProcessInfo, *getProcessInfo(proc))
+			if proc.GetState() != process.RUNNING {
+				failed = append(failed, proc.GetName())
+			}
+		}
+		if len(failed) > 0 {
+			log.WithFields(log.Fields{"program": args.Name, "failed": failed}).Warn("abort the rolling restart")
+			return errors.New("abort the rolling restart, fail to start the processes").As(args.Name, failed)
+		}
+		if end < len(procs) && args.Pause > 0 {
+			time.Sleep(args.Pause)
+		}
+	}
+	return nil
+}
+
+// find the processes for the rolling restart by the name, the name can be:
+//
+//  group: or group:*  - all the processes of the group
+//  group:name         - the process of the group
+//  program            - the numprocs instances of the program, or the process of the name
+func (s *Supervisor) findRollingProcesses(name string) []*process.Process {
+	if strings.HasSuffix(name, ":") {
+		name += "*"
+	}
+	procs := []*process.Process{}
+	if strings.Contains(name, ":") {
+		procs = s.procMgr.FindMatch(name)
+	} else {
+		s.procMgr.ForEachProcess(func(proc *process.Process) {
+			if proc.GetConfig().Program == name {
+				procs = append(procs, proc)
+			}
+		})
+		if len(procs) <= 0 {
+			procs = s.procMgr.FindMatch(name)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		numI, numJ := procs[i].GetConfig().GetInt("process_num", 0), procs[j].GetConfig().GetInt("process_num", 0)
+		if numI != numJ {
+			return numI < numJ
+		}
+		return procs[i].GetName() < procs[j].GetName()
+	})
+	return procs
+}
+
 func (s *Supervisor) SignalProcess(args *types.ProcessSignal, reply *rpcclient.StatusReply) error {
 	procs := s.procMgr.FindMatch(args.Name)
 	if len(procs) <= 0 {
@@ -421,9 +567,177 @@ func (s *Supervisor) SignalProcess(args *types.ProcessSignal, reply *rpcclient.S
 		return fmt.Errorf("No process named %s", args.Name)
 	}
 	sig, err := signals.ToSignal(args.Signal)
-	if err == nil {
-		for _, proc := range procs {
-			proc.Signal(sig, false)
+	if err != nil {
+		reply.Success = false
+		return err
+	}
+	for _, proc := range procs {
+		proc.Signal(sig, false)
+	}
+	reply.Success = true
+	return nil
+}
+
+// add or remove the process instances of a numprocs program at runtime,
+// the surplus instances are stopped from the highest number down.
+func (s *Supervisor) ScaleProgram(args *rpcclient.ScaleProgramArg, reply *rpcclient.ScaleProgramRet) error {
+	log.WithFields(log.Fields{"program": args.Name, "numprocs": args.Numprocs}).Info("scale program")
+	// the config is changed like the reload
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	added, removed, err := s.config.ScaleProgram(args.Name, args.Numprocs)
+	if err != nil {
+		return errors.As(err)
+	}
+	numprocs := args.Numprocs
+	confNumprocs, _ := s.config.GetConfigNumprocs(args.Name)
+	s.setScaledNumprocs(args.Name, &numprocs, &confNumprocs)
+
+	for _, name := range removed {
+		proc := s.procMgr.Remove(name)
+		if proc != nil {
+			log.WithFields(log.Fields{"program": name}).Info("the process is scaled in and will be stopped")
+			proc.Stop(true)
+		}
+		s.setStoppedByUser(name, false)
+	}
+	for _, name := range added {
+		entry := s.config.GetProgram(name)
+		if entry == nil {
+			continue
+		}
+		proc := s.createProcess(entry)
+		if proc.IsAutoStart() && !s.isStoppedByUser(name) {
+			proc.Start(false)
+		}
+	}
+	s.savePorts()
+	reply.Added = added
+	reply.Removed = removed
+	return nil
+}
+
+// create the process of the instance from the [program:<template>@] section, the instance is persisted.
+func (s *Supervisor) createInstance(name string) (*process.Process, error) {
+	// the config is changed like the reload
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	entry, err := s.config.CreateInstance(name)
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	log.WithFields(log.Fields{"program": name}).Info("create the instance of template")
+	s.updateProgramState(name, func(progState *programState) {
+		progState.Instance = true
+	})
+	return s.createProcess(entry), nil
+}
+
+// stop and remove the instances created from the templates
+func (s *Supervisor) RemoveInstance(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
+	if !config.IsInstanceName(args.Name) {
+		return errors.New("not an instance of template").As(args.Name)
+	}
+	// the config is changed like the reload, the instance of the same name can't be
+	// created until the old one is stopped and removed.
+	s.reloadLock.Lock()
+	proc := s.procMgr.Remove(args.Name)
+	if proc == nil {
+		s.reloadLock.Unlock()
+		return errors.New("fail to find process").As(args.Name)
+	}
+	log.WithFields(log.Fields{"program": args.Name}).Info("remove the instance of template")
+	remove := func() {
+		defer s.reloadLock.Unlock()
+		proc.Stop(true)
+		s.config.RemoveInstance(args.Name)
+		s.updateProgramState(args.Name, func(progState *programState) {
+			*progState = programState{}
+		})
+	}
+	if args.Wait {
+		remove()
+	} else {
+		// the lock is released after the instance is stopped
+		go remove()
+	}
+	reply.Success = true
+	return nil
+}
+
+// ask the running programs to reload their configuration without a restart
+func (s *Supervisor) ReloadProcess(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
+	log.WithFields(log.Fields{"program": args.Name}).Info("reload process")
+	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 {
+		return errors.New("fail to find process").As(args.Name)
+	}
+	for _, proc := range procs {
+		if err := proc.Reload(); err != nil {
+			return errors.As(err)
+		}
+	}
+	reply.Success = true
+	return nil
+}
+
+// stop and disable the processes, the disabled processes can't be started until they are enabled
+func (s *Supervisor) DisableProcess(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
+	log.WithFields(log.Fields{"program": args.Name}).Info("disable process")
+	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 {
+		return errors.New("fail to find process").As(args.Name)
+	}
+	for _, proc := range procs {
+		s.setDisabled(proc.GetName(), true)
+		proc.SetDisabled(true)
+		proc.Stop(args.Wait)
+	}
+	reply.Success = true
+	return nil
+}
+
+// enable the disabled processes, they are not started until the user starts them
+func (s *Supervisor) EnableProcess(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
+	log.WithFields(log.Fields{"program": args.Name}).Info("enable process")
+	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 {
+		return errors.New("fail to find process").As(args.Name)
+	}
+	for _, proc := range procs {
+		s.setDisabled(proc.GetName(), false)
+		proc.SetDisabled(false)
+	}
+	reply.Success = true
+	return nil
+}
+
+// freeze the running processes, the paused processes are not restarted
+func (s *Supervisor) PauseProcess(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
+	log.WithFields(log.Fields{"program": args.Name}).Info("pause process")
+	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 {
+		return errors.New("fail to find process").As(args.Name)
+	}
+	for _, proc := range procs {
+		if err := proc.Pause(); err != nil {
+			return errors.As(err)
+		}
+	}
+	reply.Success = true
+	return nil
+}
+
+// thaw the paused processes
+func (s *Supervisor) ResumeProcess(args *StartProcessArgs, reply *rpcclient.StatusReply) error {
+	log.WithFields(log.Fields{"program": args.Name}).Info("resume process")
+	procs := s.procMgr.FindMatch(args.Name)
+	if len(procs) <= 0 {
+		return errors.New("fail to find process").As(args.Name)
+	}
+	for _, proc := range procs {
+		if err := proc.Resume(); err != nil {
+			return errors.As(err)
 		}
 	}
 	reply.Success = true
@@ -490,6 +804,9 @@ func (s *Supervisor) SendRemoteCommEvent(args *RemoteCommEvent, reply *rpcclient
 //
 //
 func (s *Supervisor) reload() (error, []string, []string, []string) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
 	//get the previous loaded programs
 	prevProgGroup := s.config.ProgramGroup.Clone()
 
@@ -506,6 +823,17 @@ func (s *Supervisor) reload() (error, []string, []string, []string) {
 	}
 
 	s.setSupervisordInfo()
+	s.procMgr.SetAdoptDir(s.getAdoptDir())
+	s.procMgr.SetNotifyDir(s.getNotifyDir())
+	firstLoad := s.adoptableChildren == nil
+	if firstLoad {
+		s.restoreState()
+		loadedProgramNames = s.restorePrograms(loadedProgramNames)
+		s.watchState()
+		s.watchDiscovery()
+	} else {
+		s.syncScaledNumprocs()
+	}
 	s.startEventListeners()
 	s.startHttpServer()
 
@@ -534,10 +862,8 @@ func (s *Supervisor) reload() (error, []string, []string, []string) {
 				log.WithFields(log.Fields{"program": name}).Info("the program not found")
 				break
 			}
-			val1 := pEntry.String()
-			val2 := cEntry.String()
 			// not need to reload when value is same.
-			if val1 == val2 {
+			if pEntry.Equal(cEntry) {
 				break
 			}
 
@@ -545,39 +871,87 @@ func (s *Supervisor) reload() (error, []string, []string, []string) {
 			log.WithFields(log.Fields{"program": name}).Info("the program reload by value changed")
 
 			// upgrade entry configuration
+			changedKeys := pEntry.ChangedKeys(cEntry)
 			proc.SetConfig(cEntry)
 
 			stoped := proc.StopedByUser()
 			autoStart := proc.IsAutoStart()
-			if stoped && autoStart {
+			if stoped && autoStart && !s.isStoppedByUser(name) {
 				proc.Start(false)
+			} else if !proc.Stoped() {
+				s.applyProgramChange(proc, changedKeys)
 			}
 			break
 		}
 	}
 
 	// checking add
+	autoStartProcs := []*process.Process{}
 	addedProgramNames := util.Sub(loadedProgramNames, prevProgramNames)
 	for _, name := range addedProgramNames {
 		for _, cEntry := range curPrograms {
 			if name != cEntry.GetProgramName() {
 				continue
 			}
-			proc := s.procMgr.CreateProcess(s.getSupervisorId(), cEntry)
+			proc := s.createProcess(cEntry)
+			if s.adoptChild(proc) {
+				continue
+			}
+			if s.isStoppedByUser(name) {
+				log.WithFields(log.Fields{"program": name}).Info("the program is stopped by user, don't start it")
+				continue
+			}
 			if proc.IsAutoStart() {
-				proc.Start(false)
+				autoStartProcs = append(autoStartProcs, proc)
 			}
 		}
 	}
+	s.startInOrder(autoStartProcs, false, func(proc *process.Process, wait bool) {
+		proc.Start(wait)
+	})
+	s.savePorts()
+	s.startProxies()
+	s.notifyDiscoveryChanged()
+	s.watchConfig()
+	if firstLoad {
+		for name, child := range s.adoptableChildren {
+			log.WithFields(log.Fields{"program": name, "pid": child.Pid}).Warn("the program of the left child is not found, the child is not adopted")
+		}
+		s.adoptableChildren = map[string]*process.ChildState{}
+	}
 
 	// TODO: value change for group
 	addedGroup, changedGroup, removedGroup := s.config.ProgramGroup.Sub(prevProgGroup)
 	return err, addedGroup, changedGroup, removedGroup
 }
 
+// apply the changed configuration to the running program, it is reloaded in place
+// if only the keys in reload_keys are changed, or it is restarted.
+func (s *Supervisor) applyProgramChange(proc *process.Process, changedKeys []string) {
+	changedKeys = process.ProgramKeys(changedKeys)
+	if len(changedKeys) == 0 {
+		return
+	}
+	if proc.CanReloadKeys(changedKeys) {
+		err := proc.Reload()
+		if err == nil {
+			return
+		}
+		log.WithFields(log.Fields{"program": proc.GetName()}).Warn("fail to reload the program in place, restart it:", err)
+	}
+	log.WithFields(log.Fields{"program": proc.GetName(), "keys": changedKeys}).Info("the program is restarted by the changed configuration")
+	go proc.Restart(false)
+}
+
 func (s *Supervisor) waitForExit() {
 	for {
 		if s.isRestarting() {
+			s.stopWatchConfig()
+			s.stopProxies()
+			s.stopWatchers()
+			s.rpcServer.Stop()
+			// the adoptable children are adopted by the next supervisor like after supd restarts
+			s.detachChildren()
 			s.procMgr.StopAllProcesses()
 			break
 		}
@@ -585,11 +959,18 @@ func (s *Supervisor) waitForExit() {
 	}
 }
 
+// create the process of the program, it is disabled if the user disabled it.
+func (s *Supervisor) createProcess(entry *config.ConfigEntry) *process.Process {
+	proc := s.procMgr.CreateProcess(s.getSupervisorId(), entry)
+	proc.SetDisabled(s.isDisabled(proc.GetName()))
+	return proc
+}
+
 func (s *Supervisor) createPrograms(prevPrograms []string) {
 
 	programs := s.config.GetProgramNames()
 	for _, entry := range s.config.GetPrograms() {
-		s.procMgr.CreateProcess(s.getSupervisorId(), entry)
+		s.createProcess(entry)
 	}
 	removedPrograms := util.Sub(prevPrograms, programs)
 	for _, p := range removedPrograms {
@@ -687,7 +1068,19 @@ func toLogLevel(level string) log.Level {
 
 func (s *Supervisor) ReloadConfig(args *rpcclient.ReloadConfigArg, reply *rpcclient.ReloadConfigRet) error {
 	log.Info("start to reload config")
+	prevPrograms := s.config.ClonePrograms()
 	err, addedGroup, changedGroup, removedGroup := s.reload()
+	if err == nil {
+		added, changed, removed := diffPrograms(prevPrograms, s.config.GetPrograms())
+		log.WithFields(log.Fields{"added": added, "changed": changed, "removed": removed}).Info("the programs are reloaded")
+		events.EmitEvent(events.CreateConfigReloadedEvent(added, changed, removed))
+		for _, group := range addedGroup {
+			events.EmitEvent(events.CreateProcessGroupAddedEvent(group))
+		}
+		for _, group := range removedGroup {
+			events.EmitEvent(events.CreateProcessGroupRemovedEvent(group))
+		}
+	}
 	if len(addedGroup) > 0 {
 		log.WithFields(log.Fields{"groups": strings.Join(addedGroup, ",")}).Info("added groups")
 	}
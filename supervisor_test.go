@@ -0,0 +1,240 @@
+package supd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gwaycc/supd/process"
+	"github.com/gwaycc/supd/rpcclient"
+)
+
+func TestStartGroupWithSkippedAndDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(`[program:a]
+command=/bin/sleep 30
+startsecs=0
+condition_path_exists=%(here)s/missing
+
+[program:b]
+command=/bin/sleep 30
+startsecs=0
+
+[program:c]
+command=/bin/sleep 30
+startsecs=0
+
+[group:g]
+programs=a,b,c
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s.setDisabled("c", true)
+	for _, entry := range s.config.GetPrograms() {
+		proc := s.createProcess(entry)
+		defer proc.Stop(true)
+	}
+
+	err = s.startProcess(&StartProcessArgs{Name: "g:*", Wait: true})
+	if state := s.procMgr.Find("b").GetState(); state != process.RUNNING {
+		t.Fatalf("expect b is started with the skipped a, but it is %s", state)
+	}
+	if err == nil || !strings.Contains(err.Error(), "missing does not exist") || !strings.Contains(err.Error(), "[c]") {
+		t.Fatalf("expect a is reported as skipped and c as disabled, but got %v", err)
+	}
+	if state := s.procMgr.Find("c").GetState(); state != process.STOPPED {
+		t.Fatalf("expect the disabled c is not started, but it is %s", state)
+	}
+}
+
+// create the supervisor with the processes of the config, the processes are not started
+func newRollingTestSupervisor(t *testing.T, dir string, conf string) *Supervisor {
+	confFile := filepath.Join(dir, "supd.ini")
+	if err := ioutil.WriteFile(confFile, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSupervisor(confFile)
+	if _, err := s.config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range s.config.GetPrograms() {
+		s.createProcess(entry)
+	}
+	return s
+}
+
+// get the names of the processes
+func processNames(procs []*process.Process) string {
+	names := []string{}
+	for _, proc := range procs {
+		names = append(names, proc.GetName())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFindRollingProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-rolling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := newRollingTestSupervisor(t, dir, `[program:w]
+command=/bin/sleep 30
+process_name=w-%(process_num)s
+numprocs=3
+
+[program:x]
+command=/bin/sleep 30
+
+[program:y]
+command=/bin/sleep 30
+
+[group:g]
+programs=x,y
+`)
+	for name, expect := range map[string]string{
+		"w":   "w-1,w-2,w-3",
+		"w-1": "w-1",
+		"g:":  "x,y",
+		"g:*": "x,y",
+		"g:y": "y",
+		"x":   "x",
+		"z":   "",
+	} {
+		if names := processNames(s.findRollingProcesses(name)); names != expect {
+			t.Errorf("expect %s finds %s, but got %s", name, expect, names)
+		}
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-rolling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the instance fails to start again after its fail file is created
+	if err := ioutil.WriteFile(filepath.Join(dir, "w.sh"), []byte("test -e $1 && exit 1\nexec /bin/sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := newRollingTestSupervisor(t, dir, `[program:w]
+command=/bin/sh %(here)s/w.sh %(here)s/fail-%(process_num)s
+process_name=w-%(process_num)s
+numprocs=5
+startsecs=1
+startretries=0
+`)
+	defer s.procMgr.StopAllProcesses()
+	pids := map[string]int{}
+	start := func() {
+		var wg sync.WaitGroup
+		s.procMgr.ForEachProcess(func(proc *process.Process) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				proc.Start(true)
+			}()
+		})
+		wg.Wait()
+		s.procMgr.ForEachProcess(func(proc *process.Process) {
+			pids[proc.GetName()] = proc.GetPid()
+		})
+	}
+	start()
+
+	// the batches of 2 processes
+	reply := &rpcclient.RollingRestartRet{}
+	if err := s.RollingRestart(&rpcclient.RollingRestartArg{Name: "w", MaxUnavailable: 2}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.AllProcessInfo) != 5 {
+		t.Fatalf("expect all the processes are restarted, but got %d", len(reply.AllProcessInfo))
+	}
+	for name, pid := range pids {
+		proc := s.procMgr.Find(name)
+		if proc.GetState() != process.RUNNING || proc.GetPid() == pid {
+			t.Errorf("expect %s is restarted, but it is %s", name, proc.GetState())
+		}
+	}
+	start()
+
+	// w-3 fails in the second batch, the third batch is not restarted
+	if err := ioutil.WriteFile(filepath.Join(dir, "fail-3"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	reply = &rpcclient.RollingRestartRet{}
+	if err := s.RollingRestart(&rpcclient.RollingRestartArg{Name: "w", MaxUnavailable: 2}, reply); err == nil {
+		t.Fatal("expect the rolling restart is aborted")
+	}
+	restarted := []string{}
+	for _, info := range reply.AllProcessInfo {
+		restarted = append(restarted, info.Name)
+	}
+	if strings.Join(restarted, ",") != "w-1,w-2,w-3,w-4" {
+		t.Fatalf("expect the first 2 batches are restarted, but got %v", restarted)
+	}
+	if proc := s.procMgr.Find("w-3"); proc.GetState() == process.RUNNING {
+		t.Error("expect w-3 fails to start")
+	}
+	if proc := s.procMgr.Find("w-5"); proc.GetPid() != pids["w-5"] {
+		t.Error("expect w-5 is not restarted after the abort")
+	}
+}
+
+func TestRemoveInstance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supd-instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := newRollingTestSupervisor(t, dir, `[program:worker@]
+command=/bin/sleep 30
+startsecs=0
+`)
+	defer s.procMgr.StopAllProcesses()
+	if err := s.startProcess(&StartProcessArgs{Name: "worker@a", Wait: true}); err != nil {
+		t.Fatal(err)
+	}
+	old := s.procMgr.Find("worker@a")
+	if old == nil || old.GetState() != process.RUNNING {
+		t.Fatal("expect the instance is created and started")
+	}
+
+	// the instance of the same name is created after the old one is stopped
+	reply := &rpcclient.StatusReply{}
+	if err := s.RemoveInstance(&StartProcessArgs{Name: "worker@a", Wait: false}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.startProcess(&StartProcessArgs{Name: "worker@a", Wait: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !old.Stoped() {
+		t.Fatalf("expect the removed instance is stopped, but it is %s", old.GetState())
+	}
+	proc := s.procMgr.Find("worker@a")
+	if proc == nil || proc == old || proc.GetState() != process.RUNNING {
+		t.Fatal("expect the instance is created again")
+	}
+
+	if err := s.RemoveInstance(&StartProcessArgs{Name: "worker@a", Wait: true}, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !proc.Stoped() || s.procMgr.Find("worker@a") != nil {
+		t.Fatal("expect the instance is stopped and removed")
+	}
+	if err := s.RemoveInstance(&StartProcessArgs{Name: "worker@a", Wait: true}, reply); err == nil {
+		t.Fatal("expect the removed instance is not found")
+	}
+}